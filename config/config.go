@@ -10,39 +10,210 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
+// UserShell is the shell value that runs hooks with the user's $SHELL.
+const UserShell = "user"
+
+// Hook is a list of shell commands gmux runs outside of tmux,
+// e.g. before_start or stop. It can be written either as a plain
+// list of commands or as a mapping with additional options.
+type Hook struct {
+	Commands []string `yaml:"commands,omitempty"`
+	// Script is run as a single shell invocation after the commands,
+	// so `cd` and exported variables persist across its lines.
+	Script string `yaml:"script,omitempty"`
+	// Shell overrides the session shell for this hook.
+	Shell string `yaml:"shell,omitempty"`
+	Quiet bool   `yaml:"quiet,omitempty"`
+}
+
+func (h *Hook) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var commands []string
+	if err := unmarshal(&commands); err == nil {
+		*h = Hook{Commands: commands}
+		return nil
+	}
+
+	type rawHook Hook
+	raw := rawHook{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	*h = Hook(raw)
+	return nil
+}
+
+func (h Hook) MarshalYAML() (interface{}, error) {
+	if h.isList() {
+		return h.Commands, nil
+	}
+
+	type rawHook Hook
+	return rawHook(h), nil
+}
+
+// isList reports whether the hook has no options besides commands,
+// so it can be written back as a plain list.
+func (h Hook) isList() bool {
+	return !h.Quiet && h.Script == "" && h.Shell == ""
+}
+
+// Command is typed into a pane when it starts. It can be written either
+// as a plain string, typed and followed by Enter, or as a mapping.
+type Command struct {
+	// Text is typed into the pane as is, tmux key names like C-c
+	// included, with send-keys -l.
+	Text string `yaml:"text,omitempty"`
+	// Keys are tmux key names, e.g. C-c, Up or Escape.
+	Keys []string `yaml:"keys,omitempty"`
+	// Literal sends the keys as text, with send-keys -l.
+	// Text is always sent as is.
+	Literal bool `yaml:"literal,omitempty"`
+	// Enter is pressed after the text by default, but not after keys.
+	Enter *bool `yaml:"enter,omitempty"`
+	// Delay to wait before the command, e.g. 500ms or 2s.
+	Delay string `yaml:"delay,omitempty"`
+}
+
+func (c *Command) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var text string
+	if err := unmarshal(&text); err == nil {
+		*c = Command{Text: text}
+		return nil
+	}
+
+	type rawCommand Command
+	raw := rawCommand{}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	*c = Command(raw)
+	return nil
+}
+
+func (c Command) MarshalYAML() (interface{}, error) {
+	if c.isText() {
+		return c.Text, nil
+	}
+
+	type rawCommand Command
+	return rawCommand(c), nil
+}
+
+// isText reports whether the command has no options besides the text,
+// so it can be written back as a plain string.
+func (c Command) isText() bool {
+	return len(c.Keys) == 0 && !c.Literal && c.Enter == nil && c.Delay == ""
+}
+
+// PressEnter reports whether Enter is pressed after the command.
+func (c Command) PressEnter() bool {
+	if c.Enter != nil {
+		return *c.Enter
+	}
+
+	return len(c.Keys) == 0
+}
+
+// Commands returns plain text commands.
+func Commands(texts ...string) []Command {
+	commands := make([]Command, len(texts))
+	for i, text := range texts {
+		commands[i] = Command{Text: text}
+	}
+
+	return commands
+}
+
 type Pane struct {
-	Root     string   `yaml:"root,omitempty"`
-	Type     string   `yaml:"type,omitempty"`
-	Commands []string `yaml:"commands"`
+	// Name lets other panes split this one with split_from.
+	Name string `yaml:"name,omitempty"`
+	Root string `yaml:"root,omitempty"`
+	Type string `yaml:"type,omitempty"`
+	// Size of the pane in percent, e.g. 30%, or as a number, e.g. 10:
+	// lines for a vertical pane and columns for a horizontal one.
+	Size string `yaml:"size,omitempty"`
+	// SplitFrom names the pane to split, the previous one by default.
+	SplitFrom string            `yaml:"split_from,omitempty"`
+	Env       map[string]string `yaml:"env,omitempty"`
+	Commands  []Command         `yaml:"commands"`
+	// Panes are split from this pane.
+	Panes []Pane `yaml:"panes,omitempty"`
+	// Focus makes the pane active in its window after start.
+	Focus bool `yaml:"focus,omitempty"`
+	// Run is the command the pane runs instead of a shell,
+	// the pane closes when it exits unless RemainOnExit is set.
+	Run          string `yaml:"run,omitempty"`
+	RemainOnExit bool   `yaml:"remain_on_exit,omitempty"`
+	// WaitForShell waits for the shell prompt before typing the commands.
+	WaitForShell bool `yaml:"wait_for_shell,omitempty"`
+	// Title is shown in the pane border with pane-border-status.
+	Title string `yaml:"title,omitempty"`
+	// Options are tmux window options set for this pane only.
+	Options map[string]string `yaml:"options,omitempty"`
 }
 
 type Window struct {
-	Name        string   `yaml:"name"`
-	Root        string   `yaml:"root,omitempty"`
-	BeforeStart []string `yaml:"before_start,omitempty"`
-	Panes       []Pane   `yaml:"panes,omitempty"`
-	Commands    []string `yaml:"commands"`
-	Layout      string   `yaml:"layout,omitempty"`
-	Manual      bool     `yaml:"manual,omitempty"`
+	Name        string            `yaml:"name"`
+	Root        string            `yaml:"root,omitempty"`
+	BeforeStart []string          `yaml:"before_start,omitempty"`
+	Env         map[string]string `yaml:"env,omitempty"`
+	Panes       []Pane            `yaml:"panes,omitempty"`
+	Commands    []Command         `yaml:"commands"`
+	Layout      string            `yaml:"layout,omitempty"`
+	Manual      bool              `yaml:"manual,omitempty"`
+	// Focus makes the window active after start.
+	Focus bool `yaml:"focus,omitempty"`
+	// Options are tmux window options, e.g. synchronize-panes.
+	Options map[string]string `yaml:"options,omitempty"`
+	// Run, RemainOnExit, WaitForShell and Title apply to the window pane, see Pane.
+	Run          string `yaml:"run,omitempty"`
+	RemainOnExit bool   `yaml:"remain_on_exit,omitempty"`
+	WaitForShell bool   `yaml:"wait_for_shell,omitempty"`
+	Title        string `yaml:"title,omitempty"`
 }
 
 type Config struct {
 	Session                   string            `yaml:"session"`
 	Env                       map[string]string `yaml:"env,omitempty"`
+	EnvFile                   string            `yaml:"env_file,omitempty"`
+	Shell                     string            `yaml:"shell,omitempty"`
 	Root                      string            `yaml:"root"`
-	BeforeStart               []string          `yaml:"before_start"`
-	Stop                      []string          `yaml:"stop"`
+	BeforeStart               Hook              `yaml:"before_start"`
+	Stop                      Hook              `yaml:"stop"`
 	Windows                   []Window          `yaml:"windows"`
 	RebalanceWindowsThreshold int               `yaml:"rebalance_panes_after,omitempty"`
+	// StartupWindow is the name or the index of the window to land in,
+	// it takes precedence over focus.
+	StartupWindow string `yaml:"startup_window,omitempty"`
+	// StartupPane is the name or the index of the pane to land in
+	// within the startup window.
+	StartupPane string `yaml:"startup_pane,omitempty"`
+	// Options are tmux session options, e.g. mouse, and window options
+	// every window starts with.
+	Options map[string]string `yaml:"options,omitempty"`
+	// WaitForShell waits for the shell prompt in every pane
+	// before typing the commands.
+	WaitForShell bool `yaml:"wait_for_shell,omitempty"`
 }
 
-func EditConfig(path string) error {
-	editor := os.Getenv("EDITOR")
-	if editor == "" {
-		editor = "vim"
+// Editor returns the command of $VISUAL, or else of $EDITOR, split into its
+// arguments, like code --wait. It is vim if neither is set.
+func Editor() []string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.Fields(os.Getenv(name)); len(editor) > 0 {
+			return editor
+		}
 	}
 
-	cmd := exec.Command(editor, path)
+	return []string{"vim"}
+}
+
+func EditConfig(path string) error {
+	editor := Editor()
+
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -3,6 +3,8 @@ package config
 import (
 	"reflect"
 	"testing"
+
+	"gopkg.in/yaml.v2"
 )
 
 func TestParseConfig(t *testing.T) {
@@ -29,11 +31,11 @@ windows:
 		Windows: []Window{
 			{
 				Layout:   "tiled",
-				Commands: []string{"echo 1"},
+				Commands: Commands("echo 1"),
 				Panes: []Pane{
 					{
 						Type:     "horizontal",
-						Commands: []string{"echo 2"},
+						Commands: Commands("echo 2"),
 					},
 				},
 			},
@@ -44,3 +46,132 @@ windows:
 		t.Fatalf("expected %v, got %v", expected, config)
 	}
 }
+
+func TestParseHook(t *testing.T) {
+	yaml := `
+before_start:
+  - echo 1
+stop:
+  quiet: true
+  shell: bash -lc
+  commands:
+    - echo 2
+  script: |
+    cd /tmp
+    echo 3`
+
+	config, err := ParseConfig(yaml, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedBeforeStart := Hook{Commands: []string{"echo 1"}}
+	if !reflect.DeepEqual(expectedBeforeStart, config.BeforeStart) {
+		t.Errorf("expected %v, got %v", expectedBeforeStart, config.BeforeStart)
+	}
+
+	expectedStop := Hook{
+		Commands: []string{"echo 2"},
+		Script:   "cd /tmp\necho 3",
+		Shell:    "bash -lc",
+		Quiet:    true,
+	}
+	if !reflect.DeepEqual(expectedStop, config.Stop) {
+		t.Errorf("expected %v, got %v", expectedStop, config.Stop)
+	}
+}
+
+func TestParsePaneTree(t *testing.T) {
+	yaml := `
+session: work
+windows:
+  - name: code
+    panes:
+      - name: right
+        type: horizontal
+        size: 40%
+        panes:
+          - type: vertical
+            size: 10
+      - split_from: code
+        type: vertical`
+
+	config, err := ParseConfig(yaml, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Pane{
+		{Name: "right", Type: "horizontal", Size: "40%", Panes: []Pane{{Type: "vertical", Size: "10"}}},
+		{SplitFrom: "code", Type: "vertical"},
+	}
+	if !reflect.DeepEqual(expected, config.Windows[0].Panes) {
+		t.Errorf("expected %v, got %v", expected, config.Windows[0].Panes)
+	}
+}
+
+func TestParseCommands(t *testing.T) {
+	data := `
+session: work
+windows:
+  - name: code
+    commands:
+      - bin/server
+      - keys: [C-c, Up]
+      - text: "git commit -m '"
+        literal: true
+        enter: false
+      - text: make
+        delay: 2s`
+
+	config, err := ParseConfig(data, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noEnter := false
+	expected := []Command{
+		{Text: "bin/server"},
+		{Keys: []string{"C-c", "Up"}},
+		{Text: "git commit -m '", Literal: true, Enter: &noEnter},
+		{Text: "make", Delay: "2s"},
+	}
+	if !reflect.DeepEqual(expected, config.Windows[0].Commands) {
+		t.Errorf("expected %v, got %v", expected, config.Windows[0].Commands)
+	}
+
+	var enter []bool
+	for _, c := range config.Windows[0].Commands {
+		enter = append(enter, c.PressEnter())
+	}
+	if !reflect.DeepEqual([]bool{true, false, false, true}, enter) {
+		t.Errorf("unexpected enter %v", enter)
+	}
+
+	out, err := yaml.Marshal(config.Windows[0].Commands[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(out) != "- bin/server\n- keys:\n  - C-c\n  - Up\n" {
+		t.Errorf("unexpected yaml %q", out)
+	}
+}
+
+func TestEditor(t *testing.T) {
+	for _, v := range []struct {
+		visual, editor string
+		expected       []string
+	}{
+		{"", "", []string{"vim"}},
+		{"", "nano", []string{"nano"}},
+		{"code --wait", "nano", []string{"code", "--wait"}},
+	} {
+		t.Setenv("VISUAL", v.visual)
+		t.Setenv("EDITOR", v.editor)
+
+		if editor := Editor(); !reflect.DeepEqual(v.expected, editor) {
+			t.Errorf("expected %q, got %q", v.expected, editor)
+		}
+	}
+}
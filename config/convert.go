@@ -0,0 +1,146 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aaqaishtyaq/gmux/internal/slice"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Project files of other tools are decoded as yaml.MapSlice trees,
+// so keys gmux doesn't know can be reported as warnings.
+
+// decodeProject decodes a YAML or JSON project file.
+func decodeProject(data []byte) (yaml.MapSlice, error) {
+	if json.Valid(data) {
+		var v interface{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+
+		var err error
+		data, err = yaml.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	project := yaml.MapSlice{}
+	err := yaml.Unmarshal(data, &project)
+	return project, err
+}
+
+// lookup returns the value of key in m, nil if there is none.
+func lookup(m yaml.MapSlice, key string) interface{} {
+	for _, item := range m {
+		if scalar(item.Key) == key {
+			return item.Value
+		}
+	}
+
+	return nil
+}
+
+// scalar returns v as a string, "" for nil.
+func scalar(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+
+	return fmt.Sprint(v)
+}
+
+// scalars returns a string or a list of strings as a list.
+func scalars(v interface{}) []string {
+	list, ok := v.([]interface{})
+	if !ok {
+		if v == nil {
+			return nil
+		}
+
+		return []string{scalar(v)}
+	}
+
+	var result []string
+	for _, item := range list {
+		if item != nil {
+			result = append(result, scalar(item))
+		}
+	}
+
+	return result
+}
+
+// scalarMap returns a mapping of scalars as a map of strings, nil if it is empty.
+func scalarMap(v interface{}) map[string]string {
+	m, _ := v.(yaml.MapSlice)
+	if len(m) == 0 {
+		return nil
+	}
+
+	result := make(map[string]string, len(m))
+	for _, item := range m {
+		result[scalar(item.Key)] = scalar(item.Value)
+	}
+
+	return result
+}
+
+// optionsMap returns tmux options, with booleans written the way tmux takes them.
+func optionsMap(v interface{}) map[string]string {
+	options := scalarMap(v)
+	for key, value := range options {
+		switch value {
+		case "true":
+			options[key] = "on"
+		case "false":
+			options[key] = "off"
+		}
+	}
+
+	return options
+}
+
+// unsupported returns a warning for every key of m that is not in supported.
+func unsupported(m yaml.MapSlice, supported []string, path string) []string {
+	var warnings []string
+	for _, item := range m {
+		key := scalar(item.Key)
+		if !slice.Contains(supported, key) {
+			warnings = append(warnings, notSupported(path, key))
+		}
+	}
+
+	return warnings
+}
+
+// notSupported returns the warning for a key in the object at path.
+func notSupported(path string, key string) string {
+	if path != "" {
+		key = path + "." + key
+	}
+
+	return key + " is not supported"
+}
+
+// withCommands returns the commands with before typed first.
+func withCommands(before []string, commands []Command) []Command {
+	if len(before) == 0 {
+		return commands
+	}
+
+	return append(Commands(before...), commands...)
+}
+
+// Comments returns lines as YAML comments, to put on top of a converted file.
+func Comments(lines []string) string {
+	var b strings.Builder
+	for _, line := range lines {
+		fmt.Fprintf(&b, "# %s\n", line)
+	}
+
+	return b.String()
+}
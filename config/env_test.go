@@ -0,0 +1,50 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	data := `
+# database
+DATABASE_URL=postgres://localhost/work
+export NODE_ENV=development
+PORT = 3000 # web
+EMPTY=
+SINGLE='a $b # c'
+DOUBLE="line1\nline2 \"quoted\""
+`
+
+	env, err := ParseEnvFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"DATABASE_URL": "postgres://localhost/work",
+		"NODE_ENV":     "development",
+		"PORT":         "3000",
+		"EMPTY":        "",
+		"SINGLE":       "a $b # c",
+		"DOUBLE":       "line1\nline2 \"quoted\"",
+	}
+
+	if !reflect.DeepEqual(expected, env) {
+		t.Errorf("expected %v, got %v", expected, env)
+	}
+}
+
+func TestParseEnvFileErrors(t *testing.T) {
+	for _, data := range []string{
+		"NO_VALUE",
+		"1ST=x",
+		"BAD-NAME=x",
+		`QUOTE="x`,
+	} {
+		_, err := ParseEnvFile(data)
+		if err == nil {
+			t.Errorf("expected error for %q", data)
+		}
+	}
+}
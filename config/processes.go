@@ -0,0 +1,111 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aaqaishtyaq/gmux/internal/slice"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Process is a long running command declared in a Procfile
+// or a docker compose file.
+type Process struct {
+	Name    string
+	Command string
+}
+
+var procfileLine = regexp.MustCompile(`^([A-Za-z0-9_-]+)\s*:\s*(.+)$`)
+
+// ParseProcfile parses `name: command` lines, blank lines and # comments.
+func ParseProcfile(data string) ([]Process, error) {
+	var processes []Process
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		match := procfileLine.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("line %d: expected name: command", i+1)
+		}
+
+		processes = append(processes, Process{Name: match[1], Command: match[2]})
+	}
+
+	return processes, nil
+}
+
+// ParseCompose returns a process per service of a docker compose file,
+// in the order they are declared. Each one runs `docker compose up`
+// for its service, with -f file unless file is one of the default names.
+func ParseCompose(data []byte, file string) ([]Process, error) {
+	compose := yaml.MapSlice{}
+	err := yaml.Unmarshal(data, &compose)
+	if err != nil {
+		return nil, err
+	}
+
+	services, ok := lookup(compose, "services").(yaml.MapSlice)
+	if !ok {
+		return nil, errors.New("expected services")
+	}
+
+	command := "docker compose"
+	if !slice.Contains(ComposeFiles, file) {
+		command += " -f " + file
+	}
+
+	var processes []Process
+	for _, service := range services {
+		name := scalar(service.Key)
+		processes = append(processes, Process{Name: name, Command: command + " up " + name})
+	}
+
+	return processes, nil
+}
+
+// ComposeFiles are the names docker compose looks for, in its order.
+var ComposeFiles = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// ProcessesConfig returns a config with a pane per process, titled with
+// the process name and typing its command. Windows get at most perWindow
+// panes and are tiled when they have more than three.
+func ProcessesConfig(session string, root string, name string, processes []Process, perWindow int) Config {
+	conf := Config{Session: session, Root: root}
+	if perWindow <= 0 {
+		perWindow = len(processes)
+	}
+
+	for start := 0; start < len(processes); start += perWindow {
+		end := start + perWindow
+		if end > len(processes) {
+			end = len(processes)
+		}
+		chunk := processes[start:end]
+
+		window := Window{
+			Name:     name,
+			Title:    chunk[0].Name,
+			Commands: Commands(chunk[0].Command),
+		}
+		if start > 0 {
+			window.Name = fmt.Sprintf("%s-%d", name, start/perWindow+1)
+		}
+		if len(chunk) > 3 {
+			window.Layout = "tiled"
+		}
+
+		for _, p := range chunk[1:] {
+			window.Panes = append(window.Panes, Pane{Title: p.Name, Commands: Commands(p.Command)})
+		}
+
+		conf.Windows = append(conf.Windows, window)
+	}
+
+	return conf
+}
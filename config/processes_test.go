@@ -0,0 +1,93 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseProcfile(t *testing.T) {
+	processes, err := ParseProcfile("# dev\nweb: bin/rails server -p $PORT\n\nworker:bundle exec sidekiq\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Process{
+		{Name: "web", Command: "bin/rails server -p $PORT"},
+		{Name: "worker", Command: "bundle exec sidekiq"},
+	}
+	if !reflect.DeepEqual(expected, processes) {
+		t.Errorf("expected %v, got %v", expected, processes)
+	}
+
+	_, err = ParseProcfile("web: ok\nnot a process\n")
+	if err == nil || err.Error() != "line 2: expected name: command" {
+		t.Errorf("expected a line error, got %v", err)
+	}
+}
+
+func TestParseCompose(t *testing.T) {
+	data := []byte(`
+services:
+  db:
+    image: postgres
+  api:
+    build: ./api
+volumes:
+  data:`)
+
+	for file, command := range map[string]string{
+		"compose.yaml":    "docker compose up",
+		"compose.dev.yml": "docker compose -f compose.dev.yml up",
+	} {
+		processes, err := ParseCompose(data, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []Process{{Name: "db", Command: command + " db"}, {Name: "api", Command: command + " api"}}
+		if !reflect.DeepEqual(expected, processes) {
+			t.Errorf("%s: expected %v, got %v", file, expected, processes)
+		}
+	}
+
+	_, err := ParseCompose([]byte("version: '3'\n"), "compose.yaml")
+	if err == nil || err.Error() != "expected services" {
+		t.Errorf("expected a services error, got %v", err)
+	}
+}
+
+func TestProcessesConfig(t *testing.T) {
+	var processes []Process
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		processes = append(processes, Process{Name: name, Command: "run " + name})
+	}
+
+	conf := ProcessesConfig("work", "/code", "procs", processes, 4)
+
+	expected := Config{
+		Session: "work",
+		Root:    "/code",
+		Windows: []Window{
+			{
+				Name:     "procs",
+				Title:    "a",
+				Layout:   "tiled",
+				Commands: Commands("run a"),
+				Panes: []Pane{
+					{Title: "b", Commands: Commands("run b")},
+					{Title: "c", Commands: Commands("run c")},
+					{Title: "d", Commands: Commands("run d")},
+				},
+			},
+			{
+				Name:     "procs-2",
+				Title:    "e",
+				Commands: Commands("run e"),
+				Panes:    []Pane{{Title: "f", Commands: Commands("run f")}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(expected, conf) {
+		t.Errorf("expected %+v, got %+v", expected, conf)
+	}
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Errors of the store operations, wrapped with the path of the config.
+var (
+	ErrConfigNotFound = errors.New("config not found")
+	ErrConfigExists   = errors.New("config already exists")
+)
+
+// Store is the directory of the project configs, <project>.yaml each,
+// with the user templates in its templates directory.
+type Store struct {
+	Dir string
+}
+
+// Path returns the path of the config of the project.
+func (s Store) Path(project string) string {
+	return filepath.Join(s.Dir, project+".yaml")
+}
+
+// TemplatesDir returns the directory of the user templates.
+func (s Store) TemplatesDir() string {
+	return filepath.Join(s.Dir, "templates")
+}
+
+// List returns the projects that have a config.
+func (s Store) List() ([]string, error) {
+	return ListConfigs(s.Dir)
+}
+
+// Exists reports whether the project has a config.
+func (s Store) Exists(project string) bool {
+	info, err := os.Stat(s.Path(project))
+	return err == nil && !info.IsDir()
+}
+
+// Copy copies the config of src to dst, setting its session
+// to dst if renameSession is set. Existing configs are not overwritten.
+func (s Store) Copy(src string, dst string, renameSession bool) error {
+	data, err := s.read(src, dst)
+	if err != nil {
+		return err
+	}
+
+	if renameSession {
+		data = SetSession(data, dst)
+	}
+
+	return ioutil.WriteFile(s.Path(dst), []byte(data), 0644)
+}
+
+// Move renames the config of src to dst, setting its session
+// to dst if renameSession is set. Existing configs are not overwritten.
+func (s Store) Move(src string, dst string, renameSession bool) error {
+	data, err := s.read(src, dst)
+	if err != nil {
+		return err
+	}
+
+	if !renameSession {
+		return os.Rename(s.Path(src), s.Path(dst))
+	}
+
+	err = ioutil.WriteFile(s.Path(dst), []byte(SetSession(data, dst)), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(s.Path(src))
+}
+
+// Remove deletes the config of the project.
+func (s Store) Remove(project string) error {
+	err := CheckProject(project)
+	if err != nil {
+		return err
+	}
+
+	if !s.Exists(project) {
+		return fmt.Errorf("%s: %w", s.Path(project), ErrConfigNotFound)
+	}
+
+	return os.Remove(s.Path(project))
+}
+
+// CheckProject returns an error if the project can't name a config
+// in the store, e.g. ../work, whose path is outside of it.
+func CheckProject(project string) error {
+	if project == "" || strings.ContainsAny(project, `/\`) {
+		return fmt.Errorf("invalid project name %q", project)
+	}
+
+	return nil
+}
+
+// read returns the config of src, if dst is a valid project
+// that doesn't have a config yet.
+func (s Store) read(src string, dst string) (string, error) {
+	for _, project := range []string{src, dst} {
+		err := CheckProject(project)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if s.Exists(dst) {
+		return "", fmt.Errorf("%s: %w", s.Path(dst), ErrConfigExists)
+	}
+
+	data, err := ioutil.ReadFile(s.Path(src))
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("%s: %w", s.Path(src), ErrConfigNotFound)
+	}
+
+	return string(data), err
+}
+
+var sessionLine = regexp.MustCompile(`(?m)^session:.*$`)
+
+// SetSession rewrites the session of the config in place,
+// so the comments and the formatting are kept.
+func SetSession(data string, session string) string {
+	line := "session: " + session
+	if loc := sessionLine.FindStringIndex(data); loc != nil {
+		return data[:loc[0]] + line + data[loc[1]:]
+	}
+
+	return line + "\n" + data
+}
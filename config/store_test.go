@@ -0,0 +1,97 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	store := Store{Dir: t.TempDir()}
+	data := "# staging\nsession: work\nroot: ~/work\n"
+	if err := os.WriteFile(store.Path("work"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(store.TemplatesDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Copy("work", "work-staging", true); err != nil {
+		t.Fatal(err)
+	}
+	expectFile(t, store.Path("work-staging"), "# staging\nsession: work-staging\nroot: ~/work\n")
+	expectFile(t, store.Path("work"), data)
+
+	if err := store.Move("work-staging", "staging", false); err != nil {
+		t.Fatal(err)
+	}
+	expectFile(t, store.Path("staging"), "# staging\nsession: work-staging\nroot: ~/work\n")
+
+	projects, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"staging", "work"}; !reflect.DeepEqual(expected, projects) {
+		t.Errorf("expected %v, got %v", expected, projects)
+	}
+
+	if err := store.Copy("work", "staging", false); !errors.Is(err, ErrConfigExists) {
+		t.Errorf("expected %v, got %v", ErrConfigExists, err)
+	}
+	if err := store.Move("missing", "other", false); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("expected %v, got %v", ErrConfigNotFound, err)
+	}
+	if err := store.Copy("work", "../work", false); err == nil {
+		t.Error("expected an error for a project name with a path")
+	}
+	template := filepath.Join(store.TemplatesDir(), "rails.yaml")
+	if err := os.WriteFile(template, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Move("templates/rails", "rails", false); err == nil || store.Exists("rails") {
+		t.Errorf("expected an error for a source with a path, got %v", err)
+	}
+	if _, err := os.Stat(template); err != nil {
+		t.Errorf("expected the template to stay, got %v", err)
+	}
+
+	if err := store.Remove("staging"); err != nil {
+		t.Fatal(err)
+	}
+	if store.Exists("staging") || store.Exists("work-staging") {
+		t.Error("expected the moved and removed configs to be gone")
+	}
+	if err := store.Remove("staging"); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("expected %v, got %v", ErrConfigNotFound, err)
+	}
+	if err := store.Remove("../staging"); err == nil || err.Error() != `invalid project name "../staging"` {
+		t.Errorf("expected an invalid project name error, got %v", err)
+	}
+}
+
+func TestSetSession(t *testing.T) {
+	for data, expected := range map[string]string{
+		"root: ~/\nsession: work # main\n": "root: ~/\nsession: shop\n",
+		"root: ~/\n":                       "session: shop\nroot: ~/\n",
+	} {
+		if out := SetSession(data, "shop"); out != expected {
+			t.Errorf("expected %q, got %q", expected, out)
+		}
+	}
+}
+
+func expectFile(t *testing.T, path string, expected string) {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != expected {
+		t.Errorf("expected %s to be %q, got %q", path, expected, string(data))
+	}
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"embed"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/aaqaishtyaq/gmux/internal/slice"
+)
+
+//go:embed templates/*.yaml
+var builtinTemplates embed.FS
+
+// DefaultTemplate is the template new uses without --template.
+const DefaultTemplate = "default"
+
+// LoadTemplate returns the template called name, from <name>.yaml in dir
+// first, so user templates can replace the built-in ones.
+func LoadTemplate(dir string, name string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, name+".yaml"))
+	if os.IsNotExist(err) {
+		data, err = builtinTemplates.ReadFile("templates/" + name + ".yaml")
+		if err != nil {
+			return "", fmt.Errorf("unknown template %q, expected one of %s", name, strings.Join(ListTemplates(dir), ", "))
+		}
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// ListTemplates returns the names of the built-in templates
+// and of the ones in dir, sorted.
+func ListTemplates(dir string) []string {
+	var names []string
+	entries, _ := builtinTemplates.ReadDir("templates")
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".yaml"))
+	}
+
+	user, _ := ListConfigs(dir)
+	for _, name := range user {
+		if !slice.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// templateFuncs are the functions templates can use. quote writes
+// a value as a YAML string, whatever the user answered.
+var templateFuncs = template.FuncMap{
+	"quote": strconv.Quote,
+}
+
+// TemplateVariables returns the variables a template uses, like
+// {{ .root }}, in the order they first appear.
+func TemplateVariables(text string) ([]string, error) {
+	tmpl, err := template.New("config").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var variables []string
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				for _, arg := range cmd.Args {
+					walk(arg)
+				}
+			}
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.FieldNode:
+			if !slice.Contains(variables, n.Ident[0]) {
+				variables = append(variables, n.Ident[0])
+			}
+		}
+	}
+	walk(tmpl.Tree.Root)
+
+	return variables, nil
+}
+
+// RenderTemplate fills the template with the variables,
+// the ones that are not set are left empty.
+func RenderTemplate(text string, variables map[string]string) (string, error) {
+	tmpl, err := template.New("config").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	err = tmpl.Execute(&out, variables)
+	if err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTemplateVariables(t *testing.T) {
+	variables, err := TemplateVariables("session: {{ .session }}\nroot: {{ .root }}\n{{ if .port }}env:\n  PORT: {{ .port }}\n{{ end }}name: {{ .session }}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"session", "root", "port"}
+	if !reflect.DeepEqual(expected, variables) {
+		t.Errorf("expected %v, got %v", expected, variables)
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	out, err := RenderTemplate("session: {{ .session }}\nroot: {{ .root }}\n", map[string]string{"session": "shop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "session: shop\nroot: \n"
+	if out != expected {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+}
+
+func TestRenderTemplateQuote(t *testing.T) {
+	answers := map[string]string{"session": `shop: "dev" #1`, "root": "~/code/shop\nwindows: []"}
+	out, err := RenderTemplate("session: {{ quote .session }}\nroot: {{ quote .root }}\n", answers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := ParseConfig(out, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if conf.Session != answers["session"] || conf.Root != answers["root"] {
+		t.Errorf("expected the answers as they are, got session %q and root %q", conf.Session, conf.Root)
+	}
+}
+
+func TestBuiltinTemplates(t *testing.T) {
+	for _, name := range ListTemplates(t.TempDir()) {
+		text, err := LoadTemplate("", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := RenderTemplate(text, map[string]string{"session": "shop", "root": "~/code/shop"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conf, err := ParseConfig(out, map[string]string{})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		// A missing env_file stops the session from starting.
+		if conf.Session != "shop" || conf.Root != "~/code/shop" || len(conf.Windows) == 0 || conf.EnvFile != "" {
+			t.Errorf("%s: unexpected config %+v", name, conf)
+		}
+	}
+}
+
+func TestLoadTemplate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "rails.yaml"), []byte("session: {{ .session }}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "elixir.yaml"), []byte("session: {{ .session }}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := LoadTemplate(dir, "rails")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "session: {{ .session }}\n" {
+		t.Errorf("expected the user template to replace the built-in one, got %q", text)
+	}
+
+	expected := []string{"default", "elixir", "go", "node", "rails"}
+	if names := ListTemplates(dir); !reflect.DeepEqual(expected, names) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	_, err = LoadTemplate(dir, "django")
+	if err == nil || !strings.HasPrefix(err.Error(), `unknown template "django"`) {
+		t.Errorf("expected an unknown template error, got %v", err)
+	}
+}
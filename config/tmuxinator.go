@@ -0,0 +1,249 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	tmuxinatorKeys = []string{
+		"name", "project_name", "root", "project_root", "pre", "pre_window", "pre_tab",
+		"on_project_first_start", "on_project_start", "on_project_stop",
+		"startup_window", "startup_pane", "windows", "tabs",
+	}
+	tmuxinatorWindowKeys = []string{"root", "layout", "pre", "panes"}
+)
+
+// ImportTmuxinator converts a tmuxinator project. It returns warnings
+// for the parts gmux can't express, e.g. on_project_exit.
+func ImportTmuxinator(data []byte) (Config, []string, error) {
+	project, err := decodeProject(data)
+	if err != nil {
+		return Config{}, nil, err
+	}
+
+	warnings := unsupported(project, tmuxinatorKeys, "")
+	if strings.Contains(string(data), "<%") {
+		warnings = append(warnings, "ERB is not supported, replace <%= @settings[\"key\"] %> with ${key}")
+	}
+
+	conf := Config{
+		Session:       firstScalar(lookup(project, "name"), lookup(project, "project_name")),
+		Root:          firstScalar(lookup(project, "root"), lookup(project, "project_root")),
+		StartupWindow: scalar(lookup(project, "startup_window")),
+		StartupPane:   scalar(lookup(project, "startup_pane")),
+	}
+
+	// gmux runs before_start when the session doesn't exist yet,
+	// tmuxinator runs on_project_start on every start.
+	for _, key := range []string{"pre", "on_project_first_start", "on_project_start"} {
+		conf.BeforeStart.Commands = append(conf.BeforeStart.Commands, scalars(lookup(project, key))...)
+	}
+	conf.Stop.Commands = scalars(lookup(project, "on_project_stop"))
+
+	preWindow := scalars(lookup(project, "pre_window"))
+	if preWindow == nil {
+		preWindow = scalars(lookup(project, "pre_tab"))
+	}
+
+	windows := lookup(project, "windows")
+	if windows == nil {
+		windows = lookup(project, "tabs")
+	}
+
+	items, _ := windows.([]interface{})
+	for i, item := range items {
+		path := fmt.Sprintf("windows[%d]", i)
+		m, ok := item.(yaml.MapSlice)
+		if !ok || len(m) != 1 {
+			return Config{}, warnings, fmt.Errorf("%s: expected a window name with its commands or panes", path)
+		}
+
+		window, windowWarnings := tmuxinatorWindow(scalar(m[0].Key), m[0].Value, preWindow, path)
+		conf.Windows = append(conf.Windows, window)
+		warnings = append(warnings, windowWarnings...)
+	}
+
+	return conf, warnings, nil
+}
+
+// tmuxinatorWindow converts a window given as a command, a list of commands
+// or a mapping with panes. Every pane types preWindow first.
+func tmuxinatorWindow(name string, value interface{}, preWindow []string, path string) (Window, []string) {
+	window := Window{Name: name}
+
+	m, ok := value.(yaml.MapSlice)
+	if !ok {
+		window.Commands = withCommands(preWindow, Commands(scalars(value)...))
+		return window, nil
+	}
+
+	warnings := unsupported(m, tmuxinatorWindowKeys, path)
+	window.Root = scalar(lookup(m, "root"))
+	window.Layout = scalar(lookup(m, "layout"))
+
+	// The window pane is the first one, tmuxinator types pre in it only.
+	panes, _ := lookup(m, "panes").([]interface{})
+	for i, p := range panes {
+		pane := Pane{}
+		commands := scalars(p)
+		if named, ok := p.(yaml.MapSlice); ok && len(named) == 1 {
+			pane.Title = scalar(named[0].Key)
+			commands = scalars(named[0].Value)
+		}
+		pane.Commands = withCommands(preWindow, Commands(commands...))
+
+		if i > 0 {
+			window.Panes = append(window.Panes, pane)
+			continue
+		}
+
+		window.Title = pane.Title
+		window.Commands = pane.Commands
+	}
+
+	if len(panes) == 0 {
+		window.Commands = Commands(preWindow...)
+	}
+	window.Commands = withCommands(scalars(lookup(m, "pre")), window.Commands)
+
+	return window, warnings
+}
+
+// firstScalar returns the first value that is set.
+func firstScalar(values ...interface{}) string {
+	for _, v := range values {
+		if s := scalar(v); s != "" {
+			return s
+		}
+	}
+
+	return ""
+}
+
+// ExportTmuxinator converts the config to a tmuxinator project. It returns
+// warnings for the parts tmuxinator can't express, e.g. env or pane options.
+// Split trees are flattened into a list of panes.
+func ExportTmuxinator(conf Config) ([]byte, []string, error) {
+	e := tmuxinatorExport{}
+	e.warn(len(conf.Env) > 0, "", "env")
+	e.warn(conf.EnvFile != "", "", "env_file")
+	e.warn(conf.Shell != "", "", "shell")
+	e.warn(len(conf.Options) > 0, "", "options")
+	e.warn(conf.WaitForShell, "", "wait_for_shell")
+	e.warn(conf.RebalanceWindowsThreshold != 0, "", "rebalance_panes_after")
+	e.warn(!conf.BeforeStart.isList(), "before_start", "script, shell and quiet")
+	e.warn(!conf.Stop.isList(), "stop", "script, shell and quiet")
+
+	project := yaml.MapSlice{{Key: "name", Value: conf.Session}}
+	project = appendSet(project, "root", conf.Root)
+	if len(conf.BeforeStart.Commands) > 0 {
+		project = append(project, yaml.MapItem{Key: "on_project_first_start", Value: conf.BeforeStart.Commands})
+	}
+	if len(conf.Stop.Commands) > 0 {
+		project = append(project, yaml.MapItem{Key: "on_project_stop", Value: conf.Stop.Commands})
+	}
+	project = appendSet(project, "startup_window", conf.StartupWindow)
+	project = appendSet(project, "startup_pane", conf.StartupPane)
+
+	windows := []yaml.MapSlice{}
+	for i, w := range conf.Windows {
+		path := fmt.Sprintf("windows[%d]", i)
+		e.warn(len(w.Env) > 0, path, "env")
+		e.warn(len(w.Options) > 0, path, "options")
+		e.warn(w.Manual, path, "manual")
+		e.warn(w.Focus, path, "focus")
+		e.warn(w.Run != "" || w.RemainOnExit || w.WaitForShell, path, "run, remain_on_exit and wait_for_shell")
+
+		panes := []interface{}{tmuxinatorPane(w.Title, e.commands(w.Commands, "", path))}
+		panes = e.panes(panes, w.Panes, path)
+
+		var value interface{} = panes[0]
+		if len(panes) > 1 || w.Root != "" || w.Layout != "" || w.Title != "" {
+			window := appendSet(yaml.MapSlice{}, "root", w.Root)
+			window = appendSet(window, "layout", w.Layout)
+			value = append(window, yaml.MapItem{Key: "panes", Value: panes})
+		}
+
+		windows = append(windows, yaml.MapSlice{{Key: w.Name, Value: value}})
+	}
+	project = append(project, yaml.MapItem{Key: "windows", Value: windows})
+
+	data, err := yaml.Marshal(project)
+	return data, e.warnings, err
+}
+
+type tmuxinatorExport struct {
+	warnings []string
+}
+
+func (e *tmuxinatorExport) warn(condition bool, path string, key string) {
+	if condition {
+		e.warnings = append(e.warnings, notSupported(path, key))
+	}
+}
+
+// panes appends the panes in the order Start splits them.
+func (e *tmuxinatorExport) panes(list []interface{}, panes []Pane, path string) []interface{} {
+	for i, p := range panes {
+		panePath := fmt.Sprintf("%s.panes[%d]", path, i)
+		e.warn(p.Type != "" || p.Size != "" || p.SplitFrom != "" || len(p.Panes) > 0, panePath, "type, size, split_from and nested panes")
+		e.warn(len(p.Env) > 0, panePath, "env")
+		e.warn(len(p.Options) > 0, panePath, "options")
+		e.warn(p.Focus, panePath, "focus")
+		e.warn(p.Run != "" || p.RemainOnExit || p.WaitForShell, panePath, "run, remain_on_exit and wait_for_shell")
+
+		list = append(list, tmuxinatorPane(p.Title, e.commands(p.Commands, p.Root, panePath)))
+		list = e.panes(list, p.Panes, panePath)
+	}
+
+	return list
+}
+
+// commands returns the text of the commands, tmuxinator panes can't send keys.
+// Panes don't have a root in tmuxinator, they cd to it instead.
+func (e *tmuxinatorExport) commands(commands []Command, root string, path string) []string {
+	var texts []string
+	if root != "" {
+		texts = append(texts, "cd "+root)
+	}
+
+	for i, c := range commands {
+		e.warn(!c.isText(), fmt.Sprintf("%s.commands[%d]", path, i), "keys, literal, enter and delay")
+		if c.Text != "" {
+			texts = append(texts, c.Text)
+		}
+	}
+
+	return texts
+}
+
+// tmuxinatorPane returns a pane as a command, a list of commands
+// or a title with its commands.
+func tmuxinatorPane(title string, commands []string) interface{} {
+	var value interface{}
+	switch len(commands) {
+	case 0:
+	case 1:
+		value = commands[0]
+	default:
+		value = commands
+	}
+
+	if title != "" {
+		return yaml.MapSlice{{Key: title, Value: value}}
+	}
+
+	return value
+}
+
+// appendSet appends the key unless the value is empty.
+func appendSet(m yaml.MapSlice, key string, value string) yaml.MapSlice {
+	if value == "" {
+		return m
+	}
+
+	return append(m, yaml.MapItem{Key: key, Value: value})
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestImportTmuxinator(t *testing.T) {
+	data := `
+name: blog
+root: ~/code/blog
+on_project_first_start: bundle install
+on_project_stop: docker compose stop
+on_project_exit: echo bye
+pre_window: rbenv shell 3.2
+startup_window: editor
+startup_pane: 1
+windows:
+  - editor:
+      layout: main-vertical
+      root: app
+      synchronize: after
+      panes:
+        - vim
+        - guard:
+          - bundle exec guard
+        -
+  - server: bundle exec rails s
+  - logs:
+    - cd log
+    - tail -f development.log
+  - shell:`
+
+	conf, warnings, err := ImportTmuxinator([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pre := "rbenv shell 3.2"
+	expected := Config{
+		Session:       "blog",
+		Root:          "~/code/blog",
+		BeforeStart:   Hook{Commands: []string{"bundle install"}},
+		Stop:          Hook{Commands: []string{"docker compose stop"}},
+		StartupWindow: "editor",
+		StartupPane:   "1",
+		Windows: []Window{
+			{
+				Name:     "editor",
+				Root:     "app",
+				Layout:   "main-vertical",
+				Commands: Commands(pre, "vim"),
+				Panes: []Pane{
+					{Title: "guard", Commands: Commands(pre, "bundle exec guard")},
+					{Commands: Commands(pre)},
+				},
+			},
+			{Name: "server", Commands: Commands(pre, "bundle exec rails s")},
+			{Name: "logs", Commands: Commands(pre, "cd log", "tail -f development.log")},
+			{Name: "shell", Commands: Commands(pre)},
+		},
+	}
+	if !reflect.DeepEqual(expected, conf) {
+		t.Errorf("expected %+v, got %+v", expected, conf)
+	}
+
+	expectedWarnings := []string{"on_project_exit is not supported", "windows[0].synchronize is not supported"}
+	if !reflect.DeepEqual(expectedWarnings, warnings) {
+		t.Errorf("expected warnings %q, got %q", expectedWarnings, warnings)
+	}
+}
+
+func TestExportTmuxinator(t *testing.T) {
+	conf := Config{
+		Session:     "blog",
+		Root:        "~/code/blog",
+		Env:         map[string]string{"RAILS_ENV": "development"},
+		BeforeStart: Hook{Commands: []string{"bundle install"}},
+		Windows: []Window{
+			{
+				Name:     "editor",
+				Layout:   "main-vertical",
+				Commands: Commands("vim"),
+				Panes: []Pane{
+					{Title: "guard", Root: "spec", Commands: Commands("bundle exec guard"), Panes: []Pane{{Type: "vertical"}}},
+				},
+			},
+			{Name: "server", Commands: []Command{{Text: "bin/rails s"}, {Keys: []string{"C-l"}}}},
+			{Name: "shell"},
+		},
+	}
+
+	data, warnings, err := ExportTmuxinator(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `name: blog
+root: ~/code/blog
+on_project_first_start:
+- bundle install
+windows:
+- editor:
+    layout: main-vertical
+    panes:
+    - vim
+    - guard:
+      - cd spec
+      - bundle exec guard
+    - null
+- server: bin/rails s
+- shell: null
+`
+	if string(data) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, data)
+	}
+
+	expectedWarnings := []string{
+		"env is not supported",
+		"windows[0].panes[0].type, size, split_from and nested panes is not supported",
+		"windows[0].panes[0].panes[0].type, size, split_from and nested panes is not supported",
+		"windows[1].commands[1].keys, literal, enter and delay is not supported",
+	}
+	if !reflect.DeepEqual(expectedWarnings, warnings) {
+		t.Errorf("expected warnings %q, got %q", expectedWarnings, warnings)
+	}
+
+	// The export can be imported back.
+	imported, _, err := ImportTmuxinator(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported.Windows) != 3 || len(imported.Windows[0].Panes) != 2 || imported.Windows[0].Panes[0].Title != "guard" {
+		t.Errorf("unexpected import of the export %+v", imported)
+	}
+}
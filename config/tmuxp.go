@@ -0,0 +1,150 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	tmuxpKeys = []string{
+		"session_name", "start_directory", "before_script", "shell_command_before",
+		"environment", "options", "windows",
+	}
+	tmuxpWindowKeys = []string{
+		"window_name", "layout", "start_directory", "shell_command_before",
+		"environment", "options", "focus", "window_shell", "panes",
+	}
+	tmuxpPaneKeys    = []string{"shell_command", "start_directory", "environment", "focus", "shell"}
+	tmuxpCommandKeys = []string{"cmd", "enter", "sleep_before"}
+)
+
+// ImportTmuxp converts a tmuxp workspace, in YAML or JSON. It returns
+// warnings for the parts gmux can't express, e.g. options_after.
+func ImportTmuxp(data []byte) (Config, []string, error) {
+	workspace, err := decodeProject(data)
+	if err != nil {
+		return Config{}, nil, err
+	}
+
+	warnings := unsupported(workspace, tmuxpKeys, "")
+	conf := Config{
+		Session: scalar(lookup(workspace, "session_name")),
+		Root:    scalar(lookup(workspace, "start_directory")),
+		Env:     scalarMap(lookup(workspace, "environment")),
+		Options: optionsMap(lookup(workspace, "options")),
+	}
+
+	if script := scalar(lookup(workspace, "before_script")); script != "" {
+		conf.BeforeStart.Commands = []string{script}
+	}
+
+	before := scalars(lookup(workspace, "shell_command_before"))
+	windows, _ := lookup(workspace, "windows").([]interface{})
+	for i, item := range windows {
+		path := fmt.Sprintf("windows[%d]", i)
+		m, ok := item.(yaml.MapSlice)
+		if !ok {
+			return Config{}, warnings, fmt.Errorf("%s: expected a window", path)
+		}
+
+		window, windowWarnings := tmuxpWindow(m, before, path)
+		conf.Windows = append(conf.Windows, window)
+		warnings = append(warnings, windowWarnings...)
+	}
+
+	return conf, warnings, nil
+}
+
+// tmuxpWindow converts a window, every pane types before
+// and the window shell_command_before first.
+func tmuxpWindow(m yaml.MapSlice, before []string, path string) (Window, []string) {
+	warnings := unsupported(m, tmuxpWindowKeys, path)
+	window := Window{
+		Name:    scalar(lookup(m, "window_name")),
+		Layout:  scalar(lookup(m, "layout")),
+		Root:    scalar(lookup(m, "start_directory")),
+		Env:     scalarMap(lookup(m, "environment")),
+		Options: optionsMap(lookup(m, "options")),
+		Focus:   scalar(lookup(m, "focus")) == "true",
+		Run:     scalar(lookup(m, "window_shell")),
+	}
+
+	before = append(append([]string{}, before...), scalars(lookup(m, "shell_command_before"))...)
+
+	// The window pane is the first one.
+	panes, _ := lookup(m, "panes").([]interface{})
+	for i, p := range panes {
+		panePath := fmt.Sprintf("%s.panes[%d]", path, i)
+		pane, paneWarnings := tmuxpPane(p, panePath)
+		pane.Commands = withCommands(before, pane.Commands)
+		warnings = append(warnings, paneWarnings...)
+
+		if i > 0 {
+			window.Panes = append(window.Panes, pane)
+			continue
+		}
+
+		// The window root, env and shell are the ones of its first pane.
+		if pane.Root != "" || len(pane.Env) > 0 {
+			warnings = append(warnings, panePath+": start_directory and environment of the first pane are not supported, set them on the window")
+		}
+		if pane.Run != "" {
+			window.Run = pane.Run
+		}
+		window.Commands = pane.Commands
+	}
+
+	if len(panes) == 0 {
+		window.Commands = Commands(before...)
+	}
+
+	return window, warnings
+}
+
+// tmuxpPane converts a pane given as a command, a list of commands or a mapping.
+func tmuxpPane(p interface{}, path string) (Pane, []string) {
+	m, ok := p.(yaml.MapSlice)
+	if !ok {
+		return Pane{Commands: Commands(scalars(p)...)}, nil
+	}
+
+	warnings := unsupported(m, tmuxpPaneKeys, path)
+	pane := Pane{
+		Root:  scalar(lookup(m, "start_directory")),
+		Env:   scalarMap(lookup(m, "environment")),
+		Focus: scalar(lookup(m, "focus")) == "true",
+		Run:   scalar(lookup(m, "shell")),
+	}
+
+	commands := lookup(m, "shell_command")
+	list, ok := commands.([]interface{})
+	if !ok {
+		list = []interface{}{commands}
+	}
+
+	for i, c := range list {
+		command, ok := c.(yaml.MapSlice)
+		if !ok {
+			if c != nil {
+				pane.Commands = append(pane.Commands, Command{Text: scalar(c)})
+			}
+			continue
+		}
+
+		commandPath := fmt.Sprintf("%s.shell_command[%d]", path, i)
+		warnings = append(warnings, unsupported(command, tmuxpCommandKeys, commandPath)...)
+
+		converted := Command{Text: scalar(lookup(command, "cmd"))}
+		if enter := lookup(command, "enter"); enter != nil {
+			pressEnter := scalar(enter) == "true"
+			converted.Enter = &pressEnter
+		}
+		if sleep := lookup(command, "sleep_before"); sleep != nil {
+			converted.Delay = scalar(sleep) + "s"
+		}
+		pane.Commands = append(pane.Commands, converted)
+	}
+
+	return pane, warnings
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestImportTmuxp(t *testing.T) {
+	data := `
+session_name: api
+start_directory: ~/code/api
+before_script: ./bootstrap.sh
+shell_command_before: source .venv/bin/activate
+environment:
+  DEBUG: 1
+options:
+  mouse: true
+suppress_history: false
+windows:
+  - window_name: editor
+    layout: main-horizontal
+    focus: true
+    options:
+      synchronize-panes: false
+    options_after:
+      synchronize-panes: true
+    panes:
+      - shell_command: vim
+      - shell_command:
+          - cmd: pytest -x
+            enter: false
+            sleep_before: 0.5
+        start_directory: tests
+        focus: true
+      - null
+  - window_name: server
+    shell_command_before:
+      - export PORT=8000
+    panes:
+      - python -m api`
+
+	conf, warnings, err := ImportTmuxp([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noEnter := false
+	activate := "source .venv/bin/activate"
+	expected := Config{
+		Session:     "api",
+		Root:        "~/code/api",
+		BeforeStart: Hook{Commands: []string{"./bootstrap.sh"}},
+		Env:         map[string]string{"DEBUG": "1"},
+		Options:     map[string]string{"mouse": "on"},
+		Windows: []Window{
+			{
+				Name:     "editor",
+				Layout:   "main-horizontal",
+				Focus:    true,
+				Options:  map[string]string{"synchronize-panes": "off"},
+				Commands: Commands(activate, "vim"),
+				Panes: []Pane{
+					{
+						Root:     "tests",
+						Focus:    true,
+						Commands: append(Commands(activate), Command{Text: "pytest -x", Enter: &noEnter, Delay: "0.5s"}),
+					},
+					{Commands: Commands(activate)},
+				},
+			},
+			{Name: "server", Commands: Commands(activate, "export PORT=8000", "python -m api")},
+		},
+	}
+	if !reflect.DeepEqual(expected, conf) {
+		t.Errorf("expected %+v, got %+v", expected, conf)
+	}
+
+	expectedWarnings := []string{"suppress_history is not supported", "windows[0].options_after is not supported"}
+	if !reflect.DeepEqual(expectedWarnings, warnings) {
+		t.Errorf("expected warnings %q, got %q", expectedWarnings, warnings)
+	}
+}
+
+func TestImportTmuxpJSON(t *testing.T) {
+	data := `{
+	"session_name": "api",
+	"windows": [{"window_name": "editor", "panes": ["vim", {"shell_command": ["make", "make test"]}]}]
+}`
+
+	conf, warnings, err := ImportTmuxp([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Window{{
+		Name:     "editor",
+		Commands: Commands("vim"),
+		Panes:    []Pane{{Commands: Commands("make", "make test")}},
+	}}
+	if conf.Session != "api" || !reflect.DeepEqual(expected, conf.Windows) || len(warnings) != 0 {
+		t.Errorf("unexpected import %+v, warnings %q", conf, warnings)
+	}
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidationError is a problem with a part of the config,
+// e.g. windows[0].panes[1].type.
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+
+	return e.Path + ": " + e.Message
+}
+
+// Validate returns the problems that would make the session fail
+// to start or start differently than configured.
+func (c Config) Validate() []error {
+	var errs []error
+	add := func(path string, format string, args ...interface{}) {
+		errs = append(errs, &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch {
+	case c.Session == "":
+		add("session", "is required")
+	case strings.ContainsAny(c.Session, ":."):
+		add("session", "%q can't contain \":\" or \".\"", c.Session)
+	}
+
+	if c.RebalanceWindowsThreshold < 0 {
+		add("rebalance_panes_after", "can't be negative")
+	}
+
+	validateEnv := func(path string, env map[string]string) {
+		for _, key := range sortedKeys(env) {
+			if !ValidEnvName(key) {
+				add(path, "invalid variable name %q", key)
+			}
+		}
+	}
+	validateEnv("env", c.Env)
+
+	validateCommands := func(path string, commands []Command) {
+		for cIndex, command := range commands {
+			commandPath := fmt.Sprintf("%s.commands[%d]", path, cIndex)
+			if command.Text != "" && len(command.Keys) > 0 {
+				add(commandPath, "has both text and keys, split it into two commands")
+			}
+
+			if command.Delay != "" {
+				if d, err := time.ParseDuration(command.Delay); err != nil || d < 0 {
+					add(commandPath+".delay", "%q is not a duration, e.g. 500ms or 2s", command.Delay)
+				}
+			}
+		}
+	}
+
+	validateRun := func(path string, run string, waitForShell bool) {
+		if run != "" && waitForShell {
+			add(path+".wait_for_shell", "there is no shell to wait for, the pane runs %q", run)
+		}
+	}
+
+	// Names of the panes in each window, to check startup_pane against.
+	windowNames := make([]map[string]bool, len(c.Windows))
+	focusedWindow := -1
+	startupWindow := -1
+	for wIndex, w := range c.Windows {
+		windowPath := fmt.Sprintf("windows[%d]", wIndex)
+
+		if w.Focus {
+			if focusedWindow >= 0 {
+				add(windowPath+".focus", "windows[%d] has focus already", focusedWindow)
+			} else {
+				focusedWindow = wIndex
+			}
+		}
+
+		if c.StartupWindow != "" && w.Name == c.StartupWindow && startupWindow < 0 {
+			startupWindow = wIndex
+		}
+
+		if w.Name == "" {
+			add(windowPath+".name", "is required")
+		}
+
+		validateEnv(windowPath+".env", w.Env)
+		validateCommands(windowPath, w.Commands)
+		validateRun(windowPath, w.Run, w.WaitForShell)
+
+		// Panes can split the window pane, by the window name,
+		// and the panes created before them.
+		names := map[string]bool{w.Name: true}
+		windowNames[wIndex] = names
+		focusedPane := ""
+
+		var validatePanes func(path string, panes []Pane)
+		validatePanes = func(path string, panes []Pane) {
+			for pIndex, p := range panes {
+				panePath := fmt.Sprintf("%s.panes[%d]", path, pIndex)
+
+				switch p.Type {
+				case "", "vertical", "horizontal":
+				default:
+					add(panePath+".type", "unknown split type %q, expected vertical or horizontal", p.Type)
+				}
+
+				if p.Size != "" && !validSize(p.Size) {
+					add(panePath+".size", "%q is neither a number of lines or columns, e.g. 10, nor a percentage, e.g. 30%%", p.Size)
+				}
+
+				if p.SplitFrom != "" && !names[p.SplitFrom] {
+					add(panePath+".split_from", "there is no pane %q before this one", p.SplitFrom)
+				}
+
+				if p.Name != "" {
+					if names[p.Name] {
+						add(panePath+".name", "%q is already used in the window", p.Name)
+					}
+					names[p.Name] = true
+				}
+
+				if p.Focus {
+					if focusedPane != "" {
+						add(panePath+".focus", "%s has focus already", focusedPane)
+					} else {
+						focusedPane = panePath
+					}
+				}
+
+				validateEnv(panePath+".env", p.Env)
+				validateCommands(panePath, p.Commands)
+				validateRun(panePath, p.Run, p.WaitForShell)
+				validatePanes(panePath, p.Panes)
+			}
+		}
+		validatePanes(windowPath, w.Panes)
+	}
+
+	// Both startup_window and startup_pane can also be tmux indexes.
+	if c.StartupWindow != "" && startupWindow < 0 && !isIndex(c.StartupWindow) {
+		add("startup_window", "there is no window %q", c.StartupWindow)
+	}
+
+	if c.StartupPane != "" && !isIndex(c.StartupPane) {
+		if c.StartupWindow == "" {
+			startupWindow = focusedWindow
+			if startupWindow < 0 && len(c.Windows) > 0 {
+				startupWindow = 0
+			}
+		}
+
+		if startupWindow < 0 || !windowNames[startupWindow][c.StartupPane] {
+			add("startup_pane", "there is no pane %q in the startup window", c.StartupPane)
+		}
+	}
+
+	return errs
+}
+
+// validSize reports whether size is a positive number, optionally followed by %.
+// Percentages have to be below 100.
+func validSize(size string) bool {
+	number := strings.TrimSuffix(size, "%")
+	n, err := strconv.Atoi(number)
+	if err != nil || n <= 0 || strconv.Itoa(n) != number {
+		return false
+	}
+
+	return n < 100 || number == size
+}
+
+// isIndex reports whether s is a tmux window or pane index.
+func isIndex(s string) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 0
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
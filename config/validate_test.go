@@ -0,0 +1,130 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	for _, test := range []struct {
+		config   Config
+		expected []string
+	}{
+		{
+			Config{Session: "work", Windows: []Window{{Name: "code", Panes: []Pane{{Type: "vertical"}}}}},
+			nil,
+		},
+		{
+			Config{},
+			[]string{"session: is required"},
+		},
+		{
+			Config{
+				Session:                   "my.work",
+				Env:                       map[string]string{"1X": ""},
+				RebalanceWindowsThreshold: -1,
+				Windows: []Window{
+					{Name: "code"},
+					{Name: "code", Env: map[string]string{"A-B": ""}},
+					{Panes: []Pane{{Type: "diagonal", Env: map[string]string{"OK": "", "": ""}}}},
+				},
+			},
+			[]string{
+				`session: "my.work" can't contain ":" or "."`,
+				"rebalance_panes_after: can't be negative",
+				`env: invalid variable name "1X"`,
+				`windows[1].env: invalid variable name "A-B"`,
+				"windows[2].name: is required",
+				`windows[2].panes[0].type: unknown split type "diagonal", expected vertical or horizontal`,
+				`windows[2].panes[0].env: invalid variable name ""`,
+			},
+		},
+		{
+			Config{
+				Session: "work",
+				Windows: []Window{{
+					Name: "code",
+					Panes: []Pane{
+						{Name: "right", Type: "horizontal", Size: "40%", Panes: []Pane{
+							{Name: "logs", SplitFrom: "later", Size: "0"},
+						}},
+						{Name: "right", SplitFrom: "logs", Size: "100%"},
+						{SplitFrom: "code", Size: "12"},
+						{Name: "later", Size: "10 lines"},
+					},
+				}},
+			},
+			[]string{
+				`windows[0].panes[0].panes[0].size: "0" is neither a number of lines or columns, e.g. 10, nor a percentage, e.g. 30%`,
+				`windows[0].panes[0].panes[0].split_from: there is no pane "later" before this one`,
+				`windows[0].panes[1].size: "100%" is neither a number of lines or columns, e.g. 10, nor a percentage, e.g. 30%`,
+				`windows[0].panes[1].name: "right" is already used in the window`,
+				`windows[0].panes[3].size: "10 lines" is neither a number of lines or columns, e.g. 10, nor a percentage, e.g. 30%`,
+			},
+		},
+		{
+			Config{
+				Session:       "work",
+				StartupWindow: "code",
+				StartupPane:   "logs",
+				Windows: []Window{
+					{Name: "code", Focus: true, Panes: []Pane{{Name: "logs", Focus: true}, {Focus: true}}},
+					{Name: "shell", Focus: true},
+				},
+			},
+			[]string{
+				"windows[0].panes[1].focus: windows[0].panes[0] has focus already",
+				"windows[1].focus: windows[0] has focus already",
+			},
+		},
+		{
+			Config{
+				Session:       "work",
+				StartupWindow: "tests",
+				StartupPane:   "logs",
+				Windows:       []Window{{Name: "code", Panes: []Pane{{Name: "logs"}}}},
+			},
+			[]string{
+				`startup_window: there is no window "tests"`,
+				`startup_pane: there is no pane "logs" in the startup window`,
+			},
+		},
+		{
+			Config{
+				Session:     "work",
+				StartupPane: "logs",
+				Windows:     []Window{{Name: "code"}, {Name: "logs", Focus: true, Panes: []Pane{{Name: "logs"}}}},
+			},
+			[]string{`windows[1].panes[0].name: "logs" is already used in the window`},
+		},
+		{
+			Config{Session: "work", StartupWindow: "2", StartupPane: "1", Windows: []Window{{Name: "code"}}},
+			nil,
+		},
+		{
+			Config{
+				Session: "work",
+				Windows: []Window{{
+					Name:     "code",
+					Commands: []Command{{Text: "make", Keys: []string{"Enter"}}},
+					Panes:    []Pane{{Commands: []Command{{Text: "make", Delay: "soon"}, {Keys: []string{"C-c"}, Delay: "-1s"}}}, {Run: "htop", WaitForShell: true}},
+				}},
+			},
+			[]string{
+				"windows[0].commands[0]: has both text and keys, split it into two commands",
+				`windows[0].panes[0].commands[0].delay: "soon" is not a duration, e.g. 500ms or 2s`,
+				`windows[0].panes[0].commands[1].delay: "-1s" is not a duration, e.g. 500ms or 2s`,
+				`windows[0].panes[1].wait_for_shell: there is no shell to wait for, the pane runs "htop"`,
+			},
+		},
+	} {
+		var messages []string
+		for _, err := range test.config.Validate() {
+			messages = append(messages, err.Error())
+		}
+
+		if !reflect.DeepEqual(messages, test.expected) {
+			t.Errorf("expected\n%q\ngot\n%q", test.expected, messages)
+		}
+	}
+}
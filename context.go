@@ -1,13 +1,22 @@
 package main
 
-import "os"
+import (
+	"os"
+	"strings"
+)
 
 type Context struct {
 	InsideTmuxSession bool
+	// Socket of the tmux server gmux runs inside of, from $TMUX.
+	Socket string
 }
 
 func CreateContext() Context {
-	_, tmux := os.LookupEnv("TMUX")
+	tmuxEnv, tmux := os.LookupEnv("TMUX")
 	insideTmuxSession := os.Getenv("TERM") == "screen" || tmux
-	return Context{InsideTmuxSession: insideTmuxSession}
+
+	// $TMUX is "socket,pid,session".
+	socket := strings.SplitN(tmuxEnv, ",", 2)[0]
+
+	return Context{InsideTmuxSession: insideTmuxSession, Socket: socket}
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -33,9 +34,24 @@ var environmentTestTable = []struct {
 		},
 		Context{InsideTmuxSession: true},
 	},
+	{
+		map[string]string{
+			"TMUX": "/tmp/tmux-1000/default,4242,0",
+		},
+		Context{InsideTmuxSession: true, Socket: "/tmp/tmux-1000/default"},
+	},
 }
 
 func TestCreateContext(t *testing.T) {
+	environ := os.Environ()
+	t.Cleanup(func() {
+		os.Clearenv()
+		for _, kv := range environ {
+			kv := strings.SplitN(kv, "=", 2)
+			os.Setenv(kv[0], kv[1])
+		}
+	})
+
 	os.Clearenv()
 	for _, v := range environmentTestTable {
 		for key, value := range v.environment {
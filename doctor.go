@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aaqaishtyaq/gmux/config"
+	"github.com/aaqaishtyaq/gmux/internal/slice"
+	"github.com/aaqaishtyaq/gmux/tmux"
+)
+
+// Statuses of a doctor check.
+const (
+	checkOK      = "ok"
+	checkWarning = "warning"
+	checkError   = "error"
+)
+
+var checkMarks = map[string]string{
+	checkOK:      "✓",
+	checkWarning: "!",
+	checkError:   "✗",
+}
+
+// doctorCheck is a single line of the `gmux doctor` checklist.
+type doctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Fix suggests how to resolve a warning or an error.
+	Fix string `json:"fix,omitempty"`
+}
+
+// Doctor checks everything gmux depends on: tmux, the config directory
+// with the configs in it, the editor and the debug log.
+func (gmux Gmux) Doctor(context Context, configDir string) []doctorCheck {
+	checks := gmux.checkTmux()
+	checks = append(checks, checkTmuxSession(context))
+	checks = append(checks, gmux.checkConfigs(configDir)...)
+	checks = append(checks, checkEditor(), checkDebugLog(filepath.Join(configDir, "gmux.log")))
+
+	return checks
+}
+
+func (gmux Gmux) checkTmux() []doctorCheck {
+	path, err := exec.LookPath("tmux")
+	if err != nil {
+		return []doctorCheck{{
+			Name:   "tmux",
+			Status: checkError,
+			Detail: "tmux is not found in $PATH",
+			Fix:    "install tmux, e.g. `brew install tmux` or `apt install tmux`",
+		}}
+	}
+
+	version := gmux.tmux.Version
+	if version == (tmux.Version{}) {
+		return []doctorCheck{{
+			Name:   "tmux",
+			Status: checkWarning,
+			Detail: fmt.Sprintf("can't detect the version of %s", path),
+			Fix:    "check that `tmux -V` prints a version",
+		}}
+	}
+
+	checks := []doctorCheck{{Name: "tmux", Status: checkOK, Detail: fmt.Sprintf("%s (%s)", version, path)}}
+
+	var missing []string
+	var latest tmux.Version
+	for _, c := range tmux.Capabilities {
+		if !version.Supports(c) {
+			missing = append(missing, fmt.Sprintf("%s (%s)", c.Name, c.Since))
+			latest = c.Since
+		}
+	}
+
+	if len(missing) == 0 {
+		checks = append(checks, doctorCheck{Name: "tmux capabilities", Status: checkOK, Detail: "all supported"})
+	} else {
+		checks = append(checks, doctorCheck{
+			Name:   "tmux capabilities",
+			Status: checkWarning,
+			Detail: "missing " + strings.Join(missing, ", "),
+			Fix:    fmt.Sprintf("upgrade tmux to %s or newer", latest),
+		})
+	}
+
+	return checks
+}
+
+func checkTmuxSession(context Context) doctorCheck {
+	check := doctorCheck{Name: "tmux session", Status: checkOK, Detail: "not inside tmux"}
+	if context.InsideTmuxSession {
+		check.Detail = "inside tmux"
+		if context.Socket != "" {
+			check.Detail += ", socket " + context.Socket
+		}
+	}
+
+	return check
+}
+
+// checkConfigs checks that the config directory can be read,
+// and that every config in it parses, validates and has existing roots.
+func (gmux Gmux) checkConfigs(configDir string) []doctorCheck {
+	projects, err := config.ListConfigs(configDir)
+	if err != nil {
+		fix := "check the permissions of " + configDir
+		if os.IsNotExist(err) {
+			fix = "create it with `mkdir -p " + configDir + "`"
+		}
+
+		return []doctorCheck{{Name: "config directory", Status: checkError, Detail: err.Error(), Fix: fix}}
+	}
+
+	checks := []doctorCheck{{
+		Name:   "config directory",
+		Status: checkOK,
+		Detail: fmt.Sprintf("%s, %d config(s)", configDir, len(projects)),
+	}}
+
+	for _, project := range projects {
+		checks = append(checks, gmux.checkConfig(project, filepath.Join(configDir, project+".yaml"))...)
+	}
+
+	return checks
+}
+
+func (gmux Gmux) checkConfig(project string, path string) []doctorCheck {
+	name := "config " + project
+	fix := "run `gmux edit " + project + "`"
+
+	conf, err := config.GetConfig(path, map[string]string{})
+	if err != nil {
+		return []doctorCheck{{Name: name, Status: checkError, Detail: err.Error(), Fix: fix}}
+	}
+
+	var problems []string
+	for _, err := range gmux.Validate(conf) {
+		problems = append(problems, err.Error())
+	}
+
+	if len(problems) > 0 {
+		return []doctorCheck{{Name: name, Status: checkError, Detail: strings.Join(problems, "; "), Fix: fix}}
+	}
+
+	checks := []doctorCheck{{Name: name, Status: checkOK}}
+
+	var missing []string
+	for _, root := range configRoots(conf) {
+		if info, err := os.Stat(root); err != nil || !info.IsDir() {
+			missing = append(missing, root)
+		}
+	}
+
+	if len(missing) > 0 {
+		checks = append(checks, doctorCheck{
+			Name:   "roots of " + project,
+			Status: checkWarning,
+			Detail: "missing " + strings.Join(missing, ", "),
+			Fix:    "create the directories or " + fix,
+		})
+	}
+
+	return checks
+}
+
+// configRoots returns the distinct directories the session,
+// its windows and panes start in.
+func configRoots(conf config.Config) []string {
+	var roots []string
+	add := func(root string) {
+		if !slice.Contains(roots, root) {
+			roots = append(roots, root)
+		}
+	}
+
+	var addPanes func(root string, panes []config.Pane)
+	addPanes = func(root string, panes []config.Pane) {
+		for _, p := range panes {
+			paneRoot := configRoot(root, p.Root)
+			add(paneRoot)
+			addPanes(paneRoot, p.Panes)
+		}
+	}
+
+	sessionRoot := ExpandPath(conf.Root)
+	add(sessionRoot)
+	for _, w := range conf.Windows {
+		windowRoot := configRoot(sessionRoot, w.Root)
+		add(windowRoot)
+		addPanes(windowRoot, w.Panes)
+	}
+
+	return roots
+}
+
+// checkEditor checks the editor `gmux edit` runs.
+func checkEditor() doctorCheck {
+	editor := config.Editor()
+	detail := strings.Join(editor, " ")
+	if os.Getenv("VISUAL") == "" && os.Getenv("EDITOR") == "" {
+		detail = "$VISUAL and $EDITOR are not set, using vim"
+	}
+
+	if _, err := exec.LookPath(editor[0]); err != nil {
+		return doctorCheck{
+			Name:   "editor",
+			Status: checkError,
+			Detail: fmt.Sprintf("%q is not found", editor[0]),
+			Fix:    "set $VISUAL or $EDITOR to an installed editor, with its arguments if it needs any, e.g. code --wait",
+		}
+	}
+
+	return doctorCheck{Name: "editor", Status: checkOK, Detail: detail}
+}
+
+// checkDebugLog checks that --debug can write its log.
+// The file is removed again if the check created it.
+func checkDebugLog(path string) doctorCheck {
+	_, statErr := os.Stat(path)
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		fix := "check the permissions of " + filepath.Dir(path)
+		if os.IsNotExist(err) {
+			fix = "create " + filepath.Dir(path)
+		}
+
+		return doctorCheck{Name: "debug log", Status: checkError, Detail: err.Error(), Fix: fix}
+	}
+	f.Close()
+
+	if os.IsNotExist(statErr) {
+		os.Remove(path)
+	}
+
+	return doctorCheck{Name: "debug log", Status: checkOK, Detail: path}
+}
+
+// failed reports whether any of the checks is an error.
+func failed(checks []doctorCheck) bool {
+	for _, check := range checks {
+		if check.Status == checkError {
+			return true
+		}
+	}
+
+	return false
+}
+
+func printChecks(w io.Writer, checks []doctorCheck) {
+	for _, check := range checks {
+		line := checkMarks[check.Status] + " " + check.Name
+		if check.Detail != "" {
+			line += ": " + check.Detail
+		}
+		fmt.Fprintln(w, line)
+
+		if check.Fix != "" {
+			fmt.Fprintln(w, "    fix: "+check.Fix)
+		}
+	}
+}
+
+// printChecksJSON prints the checks with the gmux version, for bug reports.
+func printChecksJSON(w io.Writer, gmuxVersion string, checks []doctorCheck) error {
+	report := struct {
+		Version string        `json:"version"`
+		Checks  []doctorCheck `json:"checks"`
+	}{gmuxVersion, checks}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aaqaishtyaq/gmux/tmux"
+)
+
+func TestDoctorTmux(t *testing.T) {
+	t.Setenv("PATH", "")
+
+	checks := Gmux{}.checkTmux()
+	if len(checks) != 1 || checks[0].Status != checkError || checks[0].Fix == "" {
+		t.Errorf("expected a missing tmux error with a fix, got %+v", checks)
+	}
+}
+
+func TestDoctorCapabilities(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no /bin/sh")
+	}
+
+	// Any executable named tmux will do, the version is not detected here.
+	bin := t.TempDir()
+	err := os.Symlink("/bin/sh", filepath.Join(bin, "tmux"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	gmux := Gmux{tmux: tmux.Tmux{Version: tmux.Version{Major: 3, Minor: 1, Suffix: "c"}}}
+	checks := gmux.checkTmux()
+
+	expected := []doctorCheck{
+		{Name: "tmux", Status: checkOK, Detail: "3.1c (" + filepath.Join(bin, "tmux") + ")"},
+		{
+			Name:   "tmux capabilities",
+			Status: checkWarning,
+			Detail: "missing new-session -e (3.2)",
+			Fix:    "upgrade tmux to 3.2 or newer",
+		},
+	}
+	if !reflect.DeepEqual(checks, expected) {
+		t.Errorf("expected %+v, got %+v", expected, checks)
+	}
+}
+
+func TestDoctorConfigs(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "gmux-missing-editor --wait")
+
+	files := map[string]string{
+		"good.yaml":    "session: good\nroot: " + dir + "\nwindows:\n  - name: code\n",
+		"broken.yaml":  "session: [\n",
+		"invalid.yaml": "session: invalid\nroot: " + dir + "\nwindows:\n  - panes:\n      - type: diagonal\n",
+		"roots.yaml":   "session: roots\nroot: " + dir + "\nwindows:\n  - name: code\n    root: missing\n",
+	}
+	for name, data := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gmux := Gmux{tmux: tmux.Tmux{Version: tmux.Version{Major: 3, Minor: 3}}}
+	checks := gmux.Doctor(Context{InsideTmuxSession: true, Socket: "/tmp/tmux-1000/default"}, dir)
+
+	statuses := make(map[string]string)
+	for _, check := range checks {
+		statuses[check.Name] = check.Status
+	}
+
+	for name, status := range map[string]string{
+		"tmux session":     checkOK,
+		"config directory": checkOK,
+		"config good":      checkOK,
+		"config broken":    checkError,
+		"config invalid":   checkError,
+		"config roots":     checkOK,
+		"roots of roots":   checkWarning,
+		"editor":           checkError,
+		"debug log":        checkOK,
+	} {
+		if statuses[name] != status {
+			t.Errorf("expected %q to be %s, got %q", name, status, statuses[name])
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "gmux.log")); !os.IsNotExist(err) {
+		t.Errorf("expected the debug log check to clean up, got %v", err)
+	}
+
+	if !failed(checks) {
+		t.Error("expected the checks to fail")
+	}
+
+	out := &bytes.Buffer{}
+	printChecks(out, checks)
+	for _, expected := range []string{
+		"✓ tmux session: inside tmux, socket /tmp/tmux-1000/default\n",
+		"! roots of roots: missing " + filepath.Join(dir, "missing") + "\n",
+		"✗ config invalid: windows[0].name: is required; windows[0].panes[0].type: unknown split type \"diagonal\", expected vertical or horizontal\n    fix: run `gmux edit invalid`\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in\n%s", expected, out)
+		}
+	}
+
+	out.Reset()
+	err := printChecksJSON(out, "1.2.0", checks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report struct {
+		Version string
+		Checks  []doctorCheck
+	}
+	err = json.Unmarshal(out.Bytes(), &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Version != "1.2.0" || !reflect.DeepEqual(report.Checks, checks) {
+		t.Errorf("unexpected JSON report\n%s", out)
+	}
+}
+
+func TestDoctorMissingConfigDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "gmux")
+
+	checks := Gmux{}.checkConfigs(dir)
+	if len(checks) != 1 || checks[0].Status != checkError || checks[0].Fix != "create it with `mkdir -p "+dir+"`" {
+		t.Errorf("expected a missing directory error, got %+v", checks)
+	}
+
+	check := checkDebugLog(filepath.Join(dir, "gmux.log"))
+	if check.Status != checkError || check.Fix != "create "+dir {
+		t.Errorf("expected a debug log error, got %+v", check)
+	}
+}
@@ -0,0 +1,804 @@
+//go:build e2e
+// +build e2e
+
+// End-to-end tests against a real tmux binary, run them with
+//
+//	go test -tags e2e .
+//
+// Every test uses its own tmux server on a socket in a temporary directory.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aaqaishtyaq/gmux/config"
+	"github.com/aaqaishtyaq/gmux/executor"
+	"github.com/aaqaishtyaq/gmux/tmux"
+
+	"gopkg.in/yaml.v2"
+)
+
+// TestMain fails fast without tmux, the e2e tag asks for a real one.
+func TestMain(m *testing.M) {
+	if _, err := exec.LookPath("tmux"); err != nil {
+		fmt.Fprintf(os.Stderr, "e2e tests need tmux: %s\n", err)
+		os.Exit(1)
+	}
+
+	os.Exit(m.Run())
+}
+
+type e2eServer struct {
+	socket string
+}
+
+// newE2EServer returns a tmux server on a throwaway socket
+// with HOME pointing to an empty temporary directory.
+func newE2EServer(t testing.TB) (*e2eServer, string) {
+	t.Helper()
+
+	// Socket paths are limited to ~100 bytes, t.TempDir can be too long on macOS.
+	dir, err := os.MkdirTemp("", "gmux")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	home, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("HOME", home)
+	t.Setenv("TMUX", "")
+	os.Unsetenv("TMUX")
+
+	server := &e2eServer{socket: filepath.Join(home, "tmux.sock")}
+	t.Cleanup(func() {
+		_ = exec.Command("tmux", "-S", server.socket, "kill-server").Run()
+		os.RemoveAll(dir)
+	})
+
+	return server, home
+}
+
+func (s *e2eServer) gmux(t testing.TB) Gmux {
+	t.Helper()
+
+	executor := executor.DefaultExecutor{}
+	tmux := tmux.Tmux{Executor: executor, Socket: s.socket, Queue: &tmux.Queue{}}
+
+	version, err := tmux.DetectVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmux.Version = version
+
+	return Gmux{tmux: tmux, executor: executor}
+}
+
+// control returns a control mode client for the server, closed with the test.
+func (s *e2eServer) control(t testing.TB) *tmux.ControlClient {
+	client := &tmux.ControlClient{Fallback: executor.DefaultExecutor{}, Socket: s.socket}
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	return client
+}
+
+func (s *e2eServer) tmux(t testing.TB, args ...string) string {
+	t.Helper()
+
+	out, err := exec.Command("tmux", append([]string{"-S", s.socket}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("tmux %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+func (s *e2eServer) hasSession(name string) bool {
+	return exec.Command("tmux", "-S", s.socket, "has-session", "-t", name+":").Run() == nil
+}
+
+// resolveRoot resolves a config root the way it is documented:
+// relative to base, or to the home directory if it starts with ~/.
+func resolveRoot(home string, base string, root string) string {
+	switch {
+	case strings.HasPrefix(root, "~/"):
+		return filepath.Join(home, root[2:])
+	case filepath.IsAbs(root):
+		return filepath.Clean(root)
+	}
+
+	return filepath.Join(base, root)
+}
+
+// expectedLayout returns, for every window started by default,
+// the window name and the roots of its panes. Directories are created on the way.
+func expectedLayout(t testing.TB, conf config.Config, home string) ([]string, [][]string) {
+	t.Helper()
+
+	var names []string
+	var roots [][]string
+
+	sessionRoot := resolveRoot(home, home, conf.Root)
+	for _, w := range conf.Windows {
+		if w.Manual {
+			continue
+		}
+
+		windowRoot := resolveRoot(home, sessionRoot, w.Root)
+		paneRoots := []string{windowRoot}
+		for _, p := range w.Panes {
+			paneRoots = append(paneRoots, resolveRoot(home, windowRoot, p.Root))
+		}
+
+		for _, root := range paneRoots {
+			if err := os.MkdirAll(root, 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		names = append(names, w.Name)
+		roots = append(roots, paneRoots)
+	}
+
+	return names, roots
+}
+
+func exampleConfigs(t *testing.T) []string {
+	t.Helper()
+
+	configs, err := filepath.Glob("testdata/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return append([]string{"example-config.yaml"}, configs...)
+}
+
+func TestE2EStartPrintStop(t *testing.T) {
+	for _, path := range exampleConfigs(t) {
+		path := path
+		t.Run(path, func(t *testing.T) {
+			server, home := newE2EServer(t)
+			gmux := server.gmux(t)
+
+			conf, err := config.GetConfig(path, map[string]string{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			names, roots := expectedLayout(t, conf, home)
+
+			err = gmux.Start(conf, Options{Detach: true}, Context{})
+			if err != nil {
+				t.Fatalf("start: %s", errorReport(err))
+			}
+
+			windows := server.tmux(t, "list-windows", "-t", conf.Session+":", "-F", "#{window_index};#{window_name}")
+			for i, w := range strings.Split(windows, "\n") {
+				parts := strings.SplitN(w, ";", 2)
+				if i >= len(names) || parts[1] != names[i] {
+					t.Fatalf("expected windows %q, got\n%s", names, windows)
+				}
+
+				panes := server.tmux(t, "list-panes", "-t", conf.Session+":"+parts[0], "-F", "#{pane_current_path}")
+				if panes != strings.Join(roots[i], "\n") {
+					t.Errorf("window %q: expected pane roots\n%s\ngot\n%s", names[i], strings.Join(roots[i], "\n"), panes)
+				}
+			}
+
+			printed, err := gmux.GetConfigFromSession(Options{Project: conf.Session}, Context{})
+			if err != nil {
+				t.Fatalf("print: %s", errorReport(err))
+			}
+
+			if len(printed.Windows) != len(names) {
+				t.Fatalf("print: expected %d windows, got %d", len(names), len(printed.Windows))
+			}
+
+			for i, w := range printed.Windows {
+				if w.Name != names[i] || w.Root != roots[i][0] || len(w.Panes) != len(roots[i])-1 {
+					t.Errorf("print: window %d does not match %q at %s with %d panes: %+v", i, names[i], roots[i][0], len(roots[i])-1, w)
+				}
+			}
+
+			err = gmux.Stop(conf, Options{}, Context{})
+			if err != nil {
+				t.Fatalf("stop: %s", errorReport(err))
+			}
+
+			if server.hasSession(conf.Session) {
+				t.Errorf("expected session %q to be stopped", conf.Session)
+			}
+		})
+	}
+}
+
+// layouts returns the layouts of the session windows without pane ids,
+// which differ between sessions.
+func (s *e2eServer) layouts(t testing.TB, session string) []string {
+	t.Helper()
+
+	var layouts []string
+	for _, layout := range strings.Split(s.tmux(t, "list-windows", "-t", session+":", "-F", "#{window_layout}"), "\n") {
+		cell, err := tmux.ParseLayout(layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var clearPanes func(c *tmux.LayoutCell)
+		clearPanes = func(c *tmux.LayoutCell) {
+			c.Pane = -1
+			for _, child := range c.Children {
+				clearPanes(child)
+			}
+		}
+		clearPanes(cell)
+
+		layouts = append(layouts, cell.String())
+	}
+
+	return layouts
+}
+
+func TestE2EPrintRoundTrip(t *testing.T) {
+	for _, path := range exampleConfigs(t) {
+		path := path
+		t.Run(path, func(t *testing.T) {
+			server, home := newE2EServer(t)
+			gmux := server.gmux(t)
+
+			conf, err := config.GetConfig(path, map[string]string{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectedLayout(t, conf, home)
+
+			err = gmux.Start(conf, Options{Detach: true}, Context{})
+			if err != nil {
+				t.Fatalf("start: %s", errorReport(err))
+			}
+			layouts := server.layouts(t, conf.Session)
+
+			printed, err := gmux.GetConfigFromSession(Options{Project: conf.Session}, Context{})
+			if err != nil {
+				t.Fatalf("print: %s", errorReport(err))
+			}
+
+			data, err := yaml.Marshal(&printed)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = gmux.tmux.StopSession(conf.Session)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			restored, err := config.ParseConfig(string(data), map[string]string{})
+			if err != nil {
+				t.Fatalf("%v\n%s", err, data)
+			}
+
+			err = gmux.Start(restored, Options{Detach: true}, Context{})
+			if err != nil {
+				t.Fatalf("start printed config: %s\n%s", errorReport(err), data)
+			}
+
+			if restoredLayouts := server.layouts(t, conf.Session); !reflect.DeepEqual(restoredLayouts, layouts) {
+				t.Errorf("expected layouts\n%s\ngot\n%s", strings.Join(layouts, "\n"), strings.Join(restoredLayouts, "\n"))
+			}
+		})
+	}
+}
+
+func TestE2EPaneTree(t *testing.T) {
+	server, home := newE2EServer(t)
+	gmux := server.gmux(t)
+
+	conf := config.Config{
+		Session: "tree",
+		Root:    home,
+		Windows: []config.Window{{
+			Name: "code",
+			Panes: []config.Pane{
+				{
+					Name: "right",
+					Type: tmux.HSplit,
+					Size: "30",
+					Panes: []config.Pane{
+						{Name: "logs", Type: tmux.VSplit, Size: "50%"},
+					},
+				},
+				{Name: "shell", SplitFrom: "code", Type: tmux.VSplit, Size: "5"},
+			},
+		}},
+	}
+
+	err := gmux.Start(conf, Options{Detach: true}, Context{})
+	if err != nil {
+		t.Fatalf("start: %s", errorReport(err))
+	}
+
+	// A detached session is 80x24, panes are listed left to right, top to bottom.
+	panes := server.tmux(t, "list-panes", "-t", "tree:code", "-F", "#{pane_width}x#{pane_height}")
+	expected := "49x18\n49x5\n30x11\n30x12"
+	if panes != expected {
+		t.Errorf("expected panes\n%s\ngot\n%s", expected, panes)
+	}
+}
+
+func TestE2ESend(t *testing.T) {
+	server, home := newE2EServer(t)
+	gmux := server.gmux(t)
+	t.Setenv("SHELL", "/bin/sh")
+
+	conf := config.Config{
+		Session: "send",
+		Root:    home,
+		Windows: []config.Window{
+			{Name: "api", Commands: config.Commands("exec sleep 30"), Panes: []config.Pane{{Title: "shell"}}},
+			{Name: "web", Panes: []config.Pane{{Title: "rails"}}},
+		},
+	}
+
+	err := gmux.Start(conf, Options{Detach: true}, Context{})
+	if err != nil {
+		t.Fatalf("start: %s", errorReport(err))
+	}
+
+	// Wait for the first api pane to run sleep instead of the shell.
+	deadline := time.Now().Add(5 * time.Second)
+	for server.tmux(t, "display-message", "-p", "-t", "send:api.0", "#{pane_current_command}") != "sleep" {
+		if time.Now().After(deadline) {
+			t.Fatal("the api pane didn't start sleep")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	sent, err := gmux.Send("send", Options{Keys: "touch sent-$TMUX_PANE", Windows: []string{"api"}, Idle: true})
+	if err != nil {
+		t.Fatalf("send: %s", errorReport(err))
+	}
+	expectEqual(t, 1, sent)
+
+	sent, err = gmux.Send("send", Options{Keys: "touch sent-$TMUX_PANE", Panes: []string{"rails"}})
+	if err != nil {
+		t.Fatalf("send: %s", errorReport(err))
+	}
+	expectEqual(t, 1, sent)
+
+	expected := []string{
+		"sent-" + server.tmux(t, "display-message", "-p", "-t", "send:api.1", "#{pane_id}"),
+		"sent-" + server.tmux(t, "display-message", "-p", "-t", "send:web.1", "#{pane_id}"),
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		files, _ := filepath.Glob(filepath.Join(home, "sent-*"))
+		if len(files) == len(expected) {
+			break
+		}
+	}
+
+	for _, name := range expected {
+		if _, err := os.Stat(filepath.Join(home, name)); err != nil {
+			t.Errorf("expected the keys to be sent to %s: %v", name, err)
+		}
+	}
+}
+
+func TestE2ECommandKeys(t *testing.T) {
+	server, home := newE2EServer(t)
+	gmux := server.gmux(t)
+	t.Setenv("SHELL", "/bin/sh")
+
+	noEnter := false
+	conf := config.Config{
+		Session: "keys",
+		Root:    home,
+		Windows: []config.Window{{
+			Name: "shell",
+			Commands: []config.Command{
+				{Text: "echo half-typed", Enter: &noEnter},
+				{Keys: []string{"C-c"}},
+				{Text: "echo literal-", Literal: true, Enter: &noEnter},
+				{Text: "Escape;", Literal: true, Delay: "10ms"},
+			},
+		}},
+	}
+
+	err := gmux.Start(conf, Options{Detach: true}, Context{})
+	if err != nil {
+		t.Fatalf("start: %s", errorReport(err))
+	}
+
+	var screen string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		screen = server.tmux(t, "capture-pane", "-p", "-t", "keys:shell")
+		if strings.Contains(screen, "\nliteral-Escape\n") {
+			break
+		}
+	}
+
+	if !strings.Contains(screen, "\nliteral-Escape\n") || strings.Contains(screen, "\nhalf-typed\n") {
+		t.Errorf("expected only the literal command to run, got\n%s", screen)
+	}
+}
+
+func TestE2ERun(t *testing.T) {
+	server, home := newE2EServer(t)
+	gmux := server.gmux(t)
+	t.Setenv("SHELL", "/bin/sh")
+
+	conf := config.Config{
+		Session:      "run",
+		Root:         home,
+		WaitForShell: true,
+		Windows: []config.Window{{
+			Name:     "servers",
+			Run:      "sleep 30",
+			Commands: config.Commands("typed"),
+			Panes: []config.Pane{
+				{Run: "sleep 0.2; exit 3", RemainOnExit: true},
+				{Commands: config.Commands("echo ready")},
+			},
+		}},
+	}
+
+	err := gmux.Start(conf, Options{Detach: true}, Context{})
+	if err != nil {
+		t.Fatalf("start: %s", errorReport(err))
+	}
+
+	// The exited pane stays with its status, the shell pane ran its command.
+	expected := `"sleep 30" 0;` + "\n" + `"sleep 0.2; exit 3" 1;3` + "\n" + ` 0;`
+	var panes string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		panes = server.tmux(t, "list-panes", "-t", "run:servers", "-F", "#{pane_start_command} #{pane_dead};#{pane_dead_status}")
+		if panes == expected {
+			break
+		}
+	}
+
+	if panes != expected {
+		t.Errorf("expected panes\n%s\ngot\n%s", expected, panes)
+	}
+
+	screen := server.tmux(t, "capture-pane", "-p", "-t", "run:servers.2")
+	if !strings.Contains(screen, "\nready") {
+		t.Errorf("expected the shell pane to run its command, got\n%s", screen)
+	}
+}
+
+func TestE2EFocus(t *testing.T) {
+	server, home := newE2EServer(t)
+	gmux := server.gmux(t)
+
+	conf := config.Config{
+		Session:     "focus",
+		Root:        home,
+		StartupPane: "logs",
+		Windows: []config.Window{
+			{Name: "shell", Panes: []config.Pane{{Focus: true}}},
+			{Name: "code", Focus: true, Panes: []config.Pane{{Name: "logs"}, {Focus: true}}},
+		},
+	}
+
+	err := gmux.Start(conf, Options{Detach: true}, Context{})
+	if err != nil {
+		t.Fatalf("start: %s", errorReport(err))
+	}
+
+	active := server.tmux(t, "display-message", "-p", "-t", "focus", "#{window_name}.#{pane_index}")
+	if active != "code.1" {
+		t.Errorf("expected code.1 to be active, got %s", active)
+	}
+
+	shell := server.tmux(t, "list-panes", "-t", "focus:shell", "-F", "#{pane_active}")
+	if shell != "0\n1" {
+		t.Errorf("expected the second pane of shell to be active, got %q", shell)
+	}
+
+	printed, err := gmux.GetConfigFromSession(Options{Project: "focus"}, Context{})
+	if err != nil {
+		t.Fatalf("print: %s", errorReport(err))
+	}
+
+	if !printed.Windows[1].Focus || !printed.Windows[1].Panes[0].Focus || !printed.Windows[0].Panes[0].Focus {
+		t.Errorf("expected print to record the focus, got %+v", printed.Windows)
+	}
+}
+
+func TestE2ETitlesAndOptions(t *testing.T) {
+	server, home := newE2EServer(t)
+	gmux := server.gmux(t)
+	if !gmux.tmux.Version.Supports(tmux.CapPaneOptions) {
+		t.Skipf("tmux %v can't set pane options", gmux.tmux.Version)
+	}
+
+	conf := config.Config{
+		Session: "options",
+		Root:    home,
+		Options: map[string]string{"status-left": `say "hi"`, "pane-border-status": "top"},
+		Windows: []config.Window{{
+			Name:    "web",
+			Options: map[string]string{"monitor-activity": "on"},
+			Panes: []config.Pane{
+				{Title: "rails", Options: map[string]string{"remain-on-exit": "on"}},
+				{Title: "sidekiq"},
+			},
+		}},
+	}
+
+	err := gmux.Start(conf, Options{Detach: true}, Context{})
+	if err != nil {
+		t.Fatalf("start: %s", errorReport(err))
+	}
+
+	titles := server.tmux(t, "list-panes", "-t", "options:web", "-F", "#{pane_title};#{pane_active}")
+	if !strings.HasSuffix(titles, "\nrails;0\nsidekiq;0") {
+		t.Errorf("expected titled panes, got %q", titles)
+	}
+
+	printed, err := gmux.GetConfigFromSession(Options{Project: "options"}, Context{})
+	if err != nil {
+		t.Fatalf("print: %s", errorReport(err))
+	}
+
+	expectEqual(t, map[string]string{"status-left": `say "hi"`}, printed.Options)
+	expectEqual(t, map[string]string{"monitor-activity": "on", "pane-border-status": "top"}, printed.Windows[0].Options)
+	expectEqual(t, "rails", printed.Windows[0].Panes[0].Title)
+	expectEqual(t, map[string]string{"remain-on-exit": "on"}, printed.Windows[0].Panes[0].Options)
+	expectEqual(t, "sidekiq", printed.Windows[0].Panes[1].Title)
+}
+
+func TestE2EEnv(t *testing.T) {
+	server, home := newE2EServer(t)
+	gmux := server.gmux(t)
+	if !gmux.tmux.Version.Supports(tmux.CapWindowEnv) {
+		t.Skipf("tmux %v can't pass env to new panes", gmux.tmux.Version)
+	}
+
+	conf, err := config.GetConfig("testdata/env.yaml", map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedLayout(t, conf, home)
+
+	err = gmux.Start(conf, Options{Detach: true}, Context{})
+	if err != nil {
+		t.Fatalf("start: %s", errorReport(err))
+	}
+
+	if env := server.tmux(t, "show-environment", "-t", "env", "NODE_ENV"); env != "NODE_ENV=development" {
+		t.Errorf("expected session env NODE_ENV=development, got %q", env)
+	}
+
+	// The pane processes are shells started with the layered env.
+	pids := strings.Split(server.tmux(t, "list-panes", "-t", "env:web", "-F", "#{pane_pid}"), "\n")
+	for i, port := range []string{"3000", "3001"} {
+		out, err := exec.Command("ps", "eww", "-o", "command=", "-p", pids[i]).Output()
+		if err != nil {
+			t.Skipf("can't read process env: %v", err)
+		}
+
+		if !strings.Contains(string(out), "PORT="+port) {
+			t.Errorf("expected pane %d to have PORT=%s, got %s", i, port, out)
+		}
+	}
+
+	err = gmux.Stop(conf, Options{}, Context{})
+	if err != nil {
+		t.Fatalf("stop: %s", errorReport(err))
+	}
+}
+
+func TestE2EControlMode(t *testing.T) {
+	server, home := newE2EServer(t)
+	gmux := server.gmux(t)
+	client := server.control(t)
+	gmux.tmux.Executor = client
+
+	conf, err := config.GetConfig("example-config.yaml", map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, _ := expectedLayout(t, conf, home)
+
+	// Nothing to connect to yet, the session is created by the fallback executor.
+	err = gmux.Start(conf, Options{Detach: true}, Context{})
+	if err != nil {
+		t.Fatalf("start: %s", errorReport(err))
+	}
+
+	windows := server.tmux(t, "list-windows", "-t", conf.Session+":", "-F", "#{window_name}")
+	if windows != strings.Join(names, "\n") {
+		t.Errorf("expected windows %q, got\n%s", names, windows)
+	}
+
+	_, err = gmux.tmux.NewWindow(conf.Session+":", "extra", home, nil, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	added := false
+	for !added {
+		select {
+		case n := <-client.Notifications():
+			added = n.Name == "window-add"
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected a window-add notification")
+		}
+	}
+
+	_, err = gmux.tmux.StopSession("missing")
+	var shellErr *executor.ShellError
+	if !errors.As(err, &shellErr) || !strings.Contains(shellErr.Stderr, "missing") {
+		t.Errorf("expected a tmux error about the missing session, got %v", err)
+	}
+
+	// The client is attached to the session, stopping it ends the connection.
+	err = gmux.Stop(conf, Options{}, Context{})
+	if err != nil {
+		t.Fatalf("stop: %s", errorReport(err))
+	}
+
+	if server.hasSession(conf.Session) {
+		t.Errorf("expected session %q to be stopped", conf.Session)
+	}
+}
+
+// BenchmarkE2EStart compares starting a session with a tmux process
+// per command, with queued commands sent to tmux together
+// and with commands sent over a control mode connection.
+func BenchmarkE2EStart(b *testing.B) {
+	conf := config.Config{Session: "bench", Root: "~/"}
+	for i := 0; i < 12; i++ {
+		conf.Windows = append(conf.Windows, config.Window{
+			Name:     fmt.Sprintf("win%d", i),
+			Layout:   tmux.MainVertical,
+			Commands: config.Commands("echo 1", "echo 2"),
+			Panes: []config.Pane{
+				{Type: tmux.HSplit, Commands: config.Commands("echo 3")},
+				{Type: tmux.VSplit, Commands: config.Commands("echo 4")},
+			},
+		})
+	}
+
+	for _, transport := range []string{"sequential", "queued", "control"} {
+		transport := transport
+		b.Run(transport, func(b *testing.B) {
+			server, _ := newE2EServer(b)
+			gmux := server.gmux(b)
+			switch transport {
+			case "sequential":
+				gmux.tmux.Queue = nil
+			case "control":
+				gmux.tmux.Executor = server.control(b)
+			}
+
+			// Keep the server running between iterations.
+			server.tmux(b, "new", "-d", "-s", "keepalive")
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				err := gmux.Start(conf, Options{Detach: true}, Context{})
+				if err != nil {
+					b.Fatalf("start: %s", errorReport(err))
+				}
+
+				_, err = gmux.tmux.StopSession(conf.Session)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestE2ESaveRestore(t *testing.T) {
+	server, home := newE2EServer(t)
+	gmux := server.gmux(t)
+	t.Setenv("SHELL", "/bin/sh")
+
+	conf := config.Config{
+		Session: "saved",
+		Root:    home,
+		Windows: []config.Window{
+			{Name: "shell", Commands: config.Commands("echo before-save"), Panes: []config.Pane{{Title: "logs"}}},
+		},
+	}
+
+	err := gmux.Start(conf, Options{Detach: true}, Context{})
+	if err != nil {
+		t.Fatalf("start: %s", errorReport(err))
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if strings.Contains(server.tmux(t, "capture-pane", "-p", "-t", "saved:shell.0"), "\nbefore-save") {
+			break
+		}
+	}
+
+	dir := filepath.Join(home, "snapshots")
+	_, err = gmux.Save("saved", dir, 0, time.Now())
+	if err != nil {
+		t.Fatalf("save: %s", errorReport(err))
+	}
+	server.tmux(t, "kill-session", "-t", "saved")
+
+	snapshot, snapshotDir, err := LoadSnapshot(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = gmux.Restore(snapshot, snapshotDir, Options{Detach: true}, Context{})
+	if err != nil {
+		t.Fatalf("restore: %s", errorReport(err))
+	}
+
+	// The restored pane prints the saved scrollback before its shell starts.
+	var screen string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		screen = server.tmux(t, "capture-pane", "-p", "-t", "saved:shell.0")
+		if strings.Contains(screen, "before-save") {
+			break
+		}
+	}
+
+	if !strings.Contains(screen, "before-save") {
+		t.Errorf("expected the scrollback to be replayed, got\n%s", screen)
+	}
+	expectEqual(t, "logs", server.tmux(t, "display-message", "-p", "-t", "saved:shell.1", "#{pane_title}"))
+}
+
+func TestE2EWatch(t *testing.T) {
+	server, home := newE2EServer(t)
+	gmux := server.gmux(t)
+	client := server.control(t)
+	gmux.tmux.Executor = client
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, "state"))
+
+	server.tmux(t, "new-session", "-d", "-s", "work")
+	server.tmux(t, "new-session", "-d", "-s", "scratch")
+
+	done := make(chan error, 1)
+	go func() {
+		done <- gmux.Watch(func() []string { return []string{"work"} }, client.Notifications(), time.Hour, 0)
+	}()
+
+	// Closing the session the watcher is attached to saves the others again.
+	waitForSnapshots := func(n int) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+			if snapshots, _ := ListSnapshots(snapshotsDir("work")); len(snapshots) >= n {
+				return
+			}
+		}
+		t.Fatalf("expected %d snapshots of work", n)
+	}
+	waitForSnapshots(1)
+	time.Sleep(time.Second)
+	server.tmux(t, "kill-session", "-t", "scratch")
+	waitForSnapshots(2)
+
+	server.tmux(t, "kill-session", "-t", "work")
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected watch to exit with the server")
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The answers when an edited config is invalid.
+const (
+	editAgain   = "e"
+	editDiscard = "d"
+	editSave    = "s"
+)
+
+// Edit opens a copy of the config at path with edit and checks it with check
+// when the editor exits, like visudo. A valid copy replaces the config,
+// otherwise ask picks whether to edit it again, discard it or save it anyway.
+// It reports whether the config was saved.
+func Edit(path string, edit func(string) error, check func(string) []error, ask func([]error) (string, error)) (bool, error) {
+	original, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	tmp, err := ioutil.TempFile("", "gmux-"+name+"-*.yaml")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(original)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for {
+		err = edit(tmp.Name())
+		if err != nil {
+			return false, err
+		}
+
+		data, err := ioutil.ReadFile(tmp.Name())
+		if err != nil {
+			return false, err
+		}
+
+		if string(data) == string(original) {
+			return false, nil
+		}
+
+		answer := editSave
+		if errs := check(string(data)); len(errs) > 0 {
+			answer, err = ask(errs)
+			if err != nil {
+				return false, err
+			}
+		}
+
+		switch answer {
+		case editSave:
+			return true, writeConfigFile(path, data, true)
+		case editDiscard:
+			return false, nil
+		}
+	}
+}
+
+// askInvalid prints the problems with an edited config on out and reads
+// what to do from in. Editing again is the default, discarding when in ends.
+func askInvalid(in *bufio.Reader, out io.Writer) func([]error) (string, error) {
+	return func(errs []error) (string, error) {
+		for {
+			for _, err := range errs {
+				fmt.Fprintf(out, "✗ %s\n", err)
+			}
+			fmt.Fprint(out, "(e)dit again, (d)iscard the changes or (s)ave anyway? [e] ")
+
+			answer, err := in.ReadString('\n')
+			if err == io.EOF && strings.TrimSpace(answer) == "" {
+				fmt.Fprintln(out)
+				return editDiscard, nil
+			}
+			if err != nil && err != io.EOF {
+				return "", err
+			}
+
+			switch answer = strings.ToLower(strings.TrimSpace(answer)); answer {
+			case "":
+				return editAgain, nil
+			case editAgain, editDiscard, editSave:
+				return answer, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEdit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "work.yaml")
+	if err := os.WriteFile(path, []byte("session: work\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every edit writes the next version, broken ones are invalid.
+	versions := []string{"session: [\n", "session: shop\n"}
+	edit := func(tmp string) error {
+		if !strings.HasSuffix(tmp, ".yaml") {
+			t.Errorf("expected a yaml copy, got %s", tmp)
+		}
+
+		data := versions[0]
+		versions = versions[1:]
+		return ioutil.WriteFile(tmp, []byte(data), 0644)
+	}
+	check := func(data string) []error {
+		if strings.Contains(data, "[") {
+			return []error{errors.New("broken")}
+		}
+		return nil
+	}
+
+	var asked []error
+	ask := func(errs []error) (string, error) {
+		asked = append(asked, errs...)
+		if len(asked) == 1 {
+			return editAgain, nil
+		}
+		return editDiscard, nil
+	}
+
+	saved, err := Edit(path, edit, check, ask)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectEqual(t, true, saved)
+	expectEqual(t, 1, len(asked))
+	expectConfigFile(t, path, "session: shop\n")
+
+	versions = []string{"session: [\n"}
+	saved, err = Edit(path, edit, check, ask)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectEqual(t, false, saved)
+	expectConfigFile(t, path, "session: shop\n")
+
+	versions = []string{"session: shop\n"}
+	saved, err = Edit(path, edit, check, ask)
+	if err != nil || saved {
+		t.Errorf("expected an unchanged config not to be saved, got %v, %v", saved, err)
+	}
+}
+
+func TestAskInvalid(t *testing.T) {
+	for input, expected := range map[string]string{
+		"\n":     editAgain,
+		"x\nS\n": editSave,
+		"d\n":    editDiscard,
+		"":       editDiscard,
+	} {
+		out := &bytes.Buffer{}
+		answer, err := askInvalid(bufio.NewReader(strings.NewReader(input)), out)([]error{errors.New("windows[0].name is required")})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectEqual(t, expected, answer)
+		if !strings.HasPrefix(out.String(), "✗ windows[0].name is required\n(e)dit again") {
+			t.Errorf("expected the errors before the question, got %q", out.String())
+		}
+	}
+}
+
+func expectConfigFile(t *testing.T, path string, expected string) {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectEqual(t, expected, string(data))
+}
@@ -1,19 +1,61 @@
 package executor
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"log"
 	"os/exec"
 	"strings"
 )
 
+// ShellError describes a command that could not be run or exited with an error.
+// Use errors.As to get it from errors returned by gmux.
 type ShellError struct {
 	Command string
 	Err     error
+	// Exit code of the command, -1 if it did not exit normally.
+	ExitCode int
+	// Stderr holds what the command wrote to stderr, if it was captured.
+	Stderr string
+	// Working directory of the command, empty for the current one.
+	Dir string
+	// ConfigPath points to the part of the config that caused the error,
+	// e.g. windows[2].panes[1].
+	ConfigPath string
+}
+
+func newShellError(cmd *exec.Cmd, err error, stderr string) *ShellError {
+	exitCode := -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+
+	return &ShellError{
+		Command:  strings.Join(cmd.Args, " "),
+		Err:      err,
+		ExitCode: exitCode,
+		Stderr:   strings.TrimSuffix(stderr, "\n"),
+		Dir:      cmd.Dir,
+	}
 }
 
 func (e *ShellError) Error() string {
-	return fmt.Sprintf("Cannot run %q. Error %v", e.Command, e.Err)
+	message := fmt.Sprintf("Cannot run %q. Error %v", e.Command, e.Err)
+	if e.ConfigPath != "" {
+		message = fmt.Sprintf("%s: %s", e.ConfigPath, message)
+	}
+
+	if e.Stderr != "" {
+		return message + "\n" + e.Stderr
+	}
+
+	return message
+}
+
+func (e *ShellError) Unwrap() error {
+	return e.Err
 }
 
 type Executor interface {
@@ -30,16 +72,20 @@ func (c DefaultExecutor) Exec(cmd *exec.Cmd) (string, error) {
 		c.Logger.Println(strings.Join(cmd.Args, " "))
 	}
 
-	output, err := cmd.CombinedOutput()
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
 	if err != nil {
 		if c.Logger != nil {
 			c.Logger.Println(err)
 		}
 
-		return "", &ShellError{strings.Join(cmd.Args, " "), err}
+		return "", newShellError(cmd, err, stderr.String())
 	}
 
-	return strings.TrimSuffix(string(output), "\n"), nil
+	return strings.TrimSuffix(stdout.String(), "\n"), nil
 }
 
 func (c DefaultExecutor) ExecQuiet(cmd *exec.Cmd) error {
@@ -47,12 +93,17 @@ func (c DefaultExecutor) ExecQuiet(cmd *exec.Cmd) error {
 		c.Logger.Println(strings.Join(cmd.Args, " "))
 	}
 
+	var stderr bytes.Buffer
+	if cmd.Stderr == nil {
+		cmd.Stderr = &stderr
+	}
+
 	err := cmd.Run()
 	if err != nil {
 		if c.Logger != nil {
 			c.Logger.Println(err)
 		}
-		return &ShellError{strings.Join(cmd.Args, " "), err}
+		return newShellError(cmd, err, stderr.String())
 	}
 	return nil
 }
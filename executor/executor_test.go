@@ -2,6 +2,7 @@ package executor
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -18,6 +19,9 @@ func TestMain(m *testing.M) {
 		fmt.Println(strings.Join(os.Args[1:], " "))
 	case "exit":
 		os.Exit(1)
+	case "fail":
+		fmt.Fprintln(os.Stderr, "something went wrong")
+		os.Exit(2)
 	}
 }
 
@@ -87,3 +91,35 @@ func TestExecQuietError(t *testing.T) {
 		t.Errorf("expected %d, got %d", 1, got)
 	}
 }
+
+func TestShellError(t *testing.T) {
+	executor := DefaultExecutor{}
+
+	cmd := exec.Command(os.Args[0], "1")
+	cmd.Env = append(os.Environ(), "TEST_MAIN=fail")
+	cmd.Dir = os.TempDir()
+
+	_, err := executor.Exec(cmd)
+
+	var shellErr *ShellError
+	if !errors.As(err, &shellErr) {
+		t.Fatalf("expected ShellError, got %v", err)
+	}
+
+	if shellErr.ExitCode != 2 {
+		t.Errorf("expected exit code %d, got %d", 2, shellErr.ExitCode)
+	}
+
+	if shellErr.Stderr != "something went wrong" {
+		t.Errorf("expected stderr %q, got %q", "something went wrong", shellErr.Stderr)
+	}
+
+	if shellErr.Dir != os.TempDir() {
+		t.Errorf("expected dir %q, got %q", os.TempDir(), shellErr.Dir)
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Errorf("expected ShellError to wrap ExitError, got %v", shellErr.Err)
+	}
+}
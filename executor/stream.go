@@ -0,0 +1,71 @@
+package executor
+
+import (
+	"strings"
+	"sync"
+)
+
+// LineWriter is an io.Writer that calls Func for every complete line
+// written to it. A trailing line without a newline is kept until Flush.
+type LineWriter struct {
+	Func func(line string)
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := strings.IndexByte(string(w.buf), '\n')
+		if i < 0 {
+			break
+		}
+
+		line := strings.TrimSuffix(string(w.buf[:i]), "\r")
+		w.buf = w.buf[i+1:]
+		w.Func(line)
+	}
+
+	return len(p), nil
+}
+
+// Flush passes the remaining incomplete line, if any, to Func.
+func (w *LineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		line := string(w.buf)
+		w.buf = nil
+		w.Func(line)
+	}
+}
+
+// Tail keeps the last Size lines passed to Add.
+type Tail struct {
+	Size int
+
+	mu    sync.Mutex
+	lines []string
+}
+
+func (t *Tail) Add(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.lines = append(t.lines, line)
+	if len(t.lines) > t.Size {
+		t.lines = t.lines[len(t.lines)-t.Size:]
+	}
+}
+
+func (t *Tail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return strings.Join(t.lines, "\n")
+}
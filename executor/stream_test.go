@@ -0,0 +1,37 @@
+package executor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := &LineWriter{Func: func(line string) {
+		lines = append(lines, line)
+	}}
+
+	for _, chunk := range []string{"first\nsec", "ond\r\n", "\nlast"} {
+		_, err := w.Write([]byte(chunk))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	w.Flush()
+
+	expected := []string{"first", "second", "", "last"}
+	if !reflect.DeepEqual(expected, lines) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+}
+
+func TestTail(t *testing.T) {
+	tail := &Tail{Size: 2}
+	for _, line := range []string{"1", "2", "3"} {
+		tail.Add(line)
+	}
+
+	if tail.String() != "2\n3" {
+		t.Errorf("expected %q, got %q", "2\n3", tail.String())
+	}
+}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/aaqaishtyaq/gmux/config"
 	"github.com/aaqaishtyaq/gmux/executor"
+	"github.com/aaqaishtyaq/gmux/internal/slice"
 	"github.com/aaqaishtyaq/gmux/tmux"
 )
 
@@ -18,15 +24,11 @@ const defaultWindowName = "gomux_def"
 // Helps with "no space for new pane" error
 const defaultRebalancePanesThreshold = 5
 
-func Contains(slice []string, s string) bool {
-	for _, e := range slice {
-		if e == s {
-			return true
-		}
-	}
-
-	return false
-}
+// How long and how often wait_for_shell checks for the shell prompt.
+const (
+	shellTimeout      = 10 * time.Second
+	shellPollInterval = 50 * time.Millisecond
+)
 
 func ExpandPath(path string) string {
 	if strings.HasPrefix(path, "~/") {
@@ -41,27 +43,150 @@ func ExpandPath(path string) string {
 	return path
 }
 
+// configRoot returns the directory of a window or a pane:
+// root if it is absolute or starts with ~/, relative to parent otherwise.
+func configRoot(parent string, root string) string {
+	expanded := ExpandPath(root)
+	if filepath.IsAbs(expanded) {
+		return expanded
+	}
+
+	return filepath.Join(parent, root)
+}
+
+// withConfigPath records which part of the config caused a ShellError.
+func withConfigPath(err error, path string) error {
+	var shellErr *executor.ShellError
+	if errors.As(err, &shellErr) && shellErr.ConfigPath == "" {
+		shellErr.ConfigPath = path
+	}
+
+	return err
+}
+
+// ErrNothingStarted matches the Start errors found before anything was
+// started, there is nothing to roll back. A running session is left alone.
+var ErrNothingStarted = errors.New("nothing was started")
+
+// nothingStartedError keeps the message of err, see ErrNothingStarted.
+type nothingStartedError struct {
+	err error
+}
+
+func (e *nothingStartedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *nothingStartedError) Unwrap() error {
+	return e.err
+}
+
+func (e *nothingStartedError) Is(target error) bool {
+	return target == ErrNothingStarted
+}
+
+func nothingStarted(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &nothingStartedError{err}
+}
+
 type Gmux struct {
 	tmux     tmux.Tmux
 	executor executor.Executor
+	// Where hook output and progress are printed, discarded if nil.
+	output io.Writer
+	// sleep waits for command delays, time.Sleep if nil.
+	sleep func(time.Duration)
 }
 
-func (gmux Gmux) execShellCommands(commands []string, path string) error {
-	for _, c := range commands {
-		cmd := exec.Command("/bin/sh", "-c", c)
-		cmd.Dir = path
+func (gmux Gmux) wait(d time.Duration) {
+	if gmux.sleep == nil {
+		time.Sleep(d)
+		return
+	}
+
+	gmux.sleep(d)
+}
+
+// at returns a copy of gmux whose queued tmux commands report the config path
+// when tmux rejects them.
+func (gmux Gmux) at(path string) Gmux {
+	gmux.tmux = gmux.tmux.At(path)
+	return gmux
+}
+
+func (gmux Gmux) out() io.Writer {
+	if gmux.output == nil {
+		return io.Discard
+	}
+
+	return gmux.output
+}
 
-		_, err := gmux.executor.Exec(cmd)
+// sessionEnv returns the session environment: variables from env_file,
+// relative to the session root, overridden by the env map.
+func sessionEnv(conf config.Config, sessionRoot string) (map[string]string, error) {
+	env := make(map[string]string)
+
+	if conf.EnvFile != "" {
+		envFile := ExpandPath(conf.EnvFile)
+		if !filepath.IsAbs(envFile) {
+			envFile = filepath.Join(sessionRoot, envFile)
+		}
+
+		fileEnv, err := config.LoadEnvFile(envFile)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("env_file: %w", err)
+		}
+
+		for key, value := range fileEnv {
+			env[key] = value
 		}
 	}
-	return nil
+
+	for key, value := range conf.Env {
+		if !config.ValidEnvName(key) {
+			return nil, fmt.Errorf("env: invalid variable name %q", key)
+		}
+		env[key] = value
+	}
+
+	return env, nil
 }
 
-func (gmux Gmux) setEnvVariables(target string, env map[string]string) error {
+// layerEnv returns a copy of base with the variables from env on top.
+func layerEnv(base map[string]string, env map[string]string) (map[string]string, error) {
+	layered := make(map[string]string, len(base)+len(env))
+	for key, value := range base {
+		layered[key] = value
+	}
+
 	for key, value := range env {
-		_, err := gmux.tmux.SetEnv(target, key, value)
+		if !config.ValidEnvName(key) {
+			return nil, fmt.Errorf("invalid variable name %q", key)
+		}
+		layered[key] = value
+	}
+
+	return layered, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (gmux Gmux) setEnvVariables(target string, env map[string]string) error {
+	for _, key := range sortedKeys(env) {
+		_, err := gmux.tmux.SetEnv(target, key, env[key])
 		if err != nil {
 			return err
 		}
@@ -85,10 +210,17 @@ func (gmux Gmux) Stop(config config.Config, options Options, context Context) er
 	if len(windows) == 0 {
 		sessionRoot := ExpandPath(config.Root)
 
-		err := gmux.execShellCommands(config.Stop, sessionRoot)
-		if err != nil {
-			return err
+		// A broken env_file only skips the hook, the session is stopped anyway.
+		env, err := sessionEnv(config, sessionRoot)
+		if err != nil && (len(config.Stop.Commands) > 0 || config.Stop.Script != "") {
+			fmt.Fprintf(gmux.out(), "Skipping the stop hook: %s\n", err)
+		} else if err == nil {
+			err = gmux.runHook("stop", config.Stop, config.Shell, sessionRoot, env)
+			if err != nil {
+				return err
+			}
 		}
+
 		_, err = gmux.tmux.StopSession(config.Session)
 		return err
 	}
@@ -100,10 +232,27 @@ func (gmux Gmux) Stop(config config.Config, options Options, context Context) er
 		}
 	}
 
-	return nil
+	return gmux.tmux.Flush()
+}
+
+// rollBack stops what a failed Start or Restore of conf started.
+// Errors found before anything was started leave the session alone.
+func (gmux Gmux) rollBack(conf config.Config, options Options, context Context, err error) error {
+	if err == nil || errors.Is(err, ErrNothingStarted) || errors.Is(err, ErrSessionRunning) {
+		return err
+	}
+
+	fmt.Fprintln(gmux.out(), "Oops, an error occurred! Rolling back...")
+	_ = gmux.Stop(conf, options, context)
+	return err
 }
 
 func (gmux Gmux) Start(config config.Config, options Options, context Context) error {
+	errs := gmux.Validate(config)
+	if len(errs) > 0 {
+		return nothingStarted(errs[0])
+	}
+
 	sessionName := config.Session + ":"
 	sessionExists := gmux.tmux.SessionExists(sessionName)
 	sessionRoot := ExpandPath(config.Root)
@@ -116,85 +265,133 @@ func (gmux Gmux) Start(config config.Config, options Options, context Context) e
 		rebalancePanesThreshold = defaultRebalancePanesThreshold
 	}
 
+	if sessionExists && len(windows) == 0 && !options.InsideCurrentSession {
+		return nothingStarted(gmux.switchOrAttach(sessionName, attach, context.InsideTmuxSession))
+	}
+
+	env, err := sessionEnv(config, sessionRoot)
+	if err != nil {
+		return nothingStarted(err)
+	}
+
 	if !sessionExists {
-		err := gmux.execShellCommands(config.BeforeStart, sessionRoot)
+		err := gmux.runHook("before_start", config.BeforeStart, config.Shell, sessionRoot, env)
 		if err != nil {
 			return err
 		}
 
-		_, err = gmux.tmux.NewSession(config.Session, sessionRoot, defaultWindowName)
+		_, err = gmux.tmux.NewSession(config.Session, sessionRoot, defaultWindowName, env)
 		if err != nil {
 			return err
 		}
 
-		err = gmux.setEnvVariables(config.Session, config.Env)
+		err = gmux.at("env").setEnvVariables(config.Session, env)
 		if err != nil {
-			return err
+			return withConfigPath(err, "env")
+		}
+
+		err = gmux.at("options").setOptions(config.Session, tmux.SessionOption, sessionOptions(config.Options))
+		if err != nil {
+			return withConfigPath(err, "options")
 		}
-	} else if len(windows) == 0 && !options.InsideCurrentSession {
-		return gmux.switchOrAttach(sessionName, attach, context.InsideTmuxSession)
 	}
 
-	for _, w := range config.Windows {
-		if (len(windows) == 0 && w.Manual) || (len(windows) > 0 && !Contains(windows, w.Name)) {
+	// Started windows by their index in the config.
+	started := make(map[int]*paneSplitter)
+
+	for wIndex, w := range config.Windows {
+		if (len(windows) == 0 && w.Manual) || (len(windows) > 0 && !slice.Contains(windows, w.Name)) {
 			continue
 		}
 
-		windowRoot := ExpandPath(w.Root)
-		if windowRoot == "" || !filepath.IsAbs(windowRoot) {
-			windowRoot = filepath.Join(sessionRoot, w.Root)
+		windowRoot := configRoot(sessionRoot, w.Root)
+
+		windowPath := fmt.Sprintf("windows[%d]", wIndex)
+		windowEnv, err := layerEnv(env, w.Env)
+		if err != nil {
+			return fmt.Errorf("%s.env: %w", windowPath, err)
 		}
 
-		window, err := gmux.tmux.NewWindow(sessionName, w.Name, windowRoot)
+		window, err := gmux.tmux.NewWindow(sessionName, w.Name, windowRoot, windowEnv, w.Run, w.RemainOnExit)
 		if err != nil {
-			return err
+			return withConfigPath(err, windowPath)
 		}
 
-		for _, c := range w.Commands {
-			err := gmux.tmux.SendKeys(window, c)
+		if w.Title != "" {
+			err = gmux.tmux.At(windowPath+".title").SetPaneTitle(window, w.Title)
 			if err != nil {
-				return err
+				return withConfigPath(err, windowPath+".title")
 			}
 		}
 
-		for pIndex, p := range w.Panes {
-			paneRoot := ExpandPath(p.Root)
-			if paneRoot == "" || !filepath.IsAbs(p.Root) {
-				paneRoot = filepath.Join(windowRoot, p.Root)
-			}
-
-			newPane, err := gmux.tmux.SplitWindow(window, p.Type, paneRoot)
-			if err != nil {
-				return err
-			}
+		err = gmux.tmux.At(windowPath+".env").RecordEnv(window, tmux.WindowOption, w.Env)
+		if err != nil {
+			return withConfigPath(err, windowPath+".env")
+		}
 
-			for _, c := range p.Commands {
-				err = gmux.tmux.SendKeys(window+"."+newPane, c)
-				if err != nil {
-					return err
-				}
-			}
+		err = gmux.at("options").setOptions(window, tmux.WindowOption, windowOptions(config.Options))
+		if err != nil {
+			return withConfigPath(err, "options")
+		}
 
-			if pIndex+1 >= rebalancePanesThreshold {
-				_, err = gmux.tmux.SelectLayout(window, tmux.Tiled)
-				if err != nil {
-					return err
-				}
+		err = gmux.at(windowPath+".options").setOptions(window, tmux.WindowOption, w.Options)
+		if err != nil {
+			return withConfigPath(err, windowPath+".options")
+		}
 
+		if (config.WaitForShell || w.WaitForShell) && w.Run == "" && len(w.Commands) > 0 {
+			err = gmux.waitForShell(window)
+			if err != nil {
+				return withConfigPath(err, windowPath+".wait_for_shell")
 			}
 		}
 
+		err = gmux.sendCommands(window, w.Commands, windowPath)
+		if err != nil {
+			return err
+		}
+
+		// Panes with sizes or split trees make their own layout,
+		// they are neither rebalanced nor get the default layout.
+		splitter := &paneSplitter{
+			gmux:               gmux,
+			window:             window,
+			named:              map[string]string{w.Name: window},
+			rebalanceThreshold: rebalancePanesThreshold,
+			waitForShell:       config.WaitForShell,
+		}
 		layout := w.Layout
-		switch layout {
-		case tmux.EvenHorizontal, tmux.EvenVertical, tmux.MainHorizontal, tmux.MainVertical:
-		default:
+		if hasPaneTree(w.Panes) {
+			splitter.rebalanceThreshold = 0
+		} else if layout == "" {
 			layout = tmux.EvenHorizontal
 		}
 
-		_, err = gmux.tmux.SelectLayout(window, layout)
+		err = splitter.split(window, w.Panes, windowPath, windowRoot, windowEnv, nil)
 		if err != nil {
 			return err
 		}
+		started[wIndex] = splitter
+
+		if splitter.focused != "" {
+			err = gmux.tmux.At(windowPath).SelectPane(splitter.focused)
+			if err != nil {
+				return withConfigPath(err, windowPath)
+			}
+		}
+
+		// Named or raw, the layout was checked before starting.
+		if layout != "" {
+			_, err = gmux.tmux.At(windowPath+".layout").SelectLayout(window, layout)
+			if err != nil {
+				return withConfigPath(err, windowPath+".layout")
+			}
+		}
+
+		err = gmux.tmux.Flush()
+		if err != nil {
+			return withConfigPath(err, windowPath)
+		}
 	}
 
 	if !options.InsideCurrentSession {
@@ -209,51 +406,415 @@ func (gmux Gmux) Start(config config.Config, options Options, context Context) e
 		}
 	}
 
+	if len(windows) == 0 && len(started) > 0 {
+		windowTarget, paneTarget := focusTargets(config, started, sessionName)
+		err := gmux.tmux.At("startup_window").SelectWindow(windowTarget)
+		if err != nil {
+			return withConfigPath(err, "startup_window")
+		}
+
+		if paneTarget != "" {
+			err = gmux.tmux.At("startup_pane").SelectPane(paneTarget)
+			if err != nil {
+				return withConfigPath(err, "startup_pane")
+			}
+		}
+	}
+
+	err = gmux.tmux.Flush()
+	if err != nil {
+		return err
+	}
+
 	if len(windows) == 0 && len(config.Windows) > 0 && !options.Detach {
-		return gmux.switchOrAttach(sessionName+config.Windows[0].Name, attach, context.InsideTmuxSession)
+		return gmux.switchOrAttach(sessionName, attach, context.InsideTmuxSession)
 	}
 
 	return nil
 }
 
-func (gmux Gmux) GetConfigFromSession(options Options, context Context) (config.Config, error) {
-	conf := config.Config{}
+// waitForShell waits until the shell in the target pane has printed its prompt,
+// which moves the cursor, so the first keys typed aren't lost while the shell starts.
+// It gives up after shellTimeout and lets the commands be typed anyway.
+func (gmux Gmux) waitForShell(target string) error {
+	err := gmux.tmux.Flush()
+	if err != nil {
+		return err
+	}
+
+	for waited := time.Duration(0); waited < shellTimeout; waited += shellPollInterval {
+		cursor, err := gmux.tmux.Display(target, "#{cursor_x},#{cursor_y}")
+		if err != nil {
+			return err
+		}
+
+		if cursor != "0,0" {
+			return nil
+		}
+
+		gmux.wait(shellPollInterval)
+	}
+
+	return nil
+}
+
+// sendCommands types the commands into the target pane. Commands with a delay
+// wait for the commands before them to be sent.
+func (gmux Gmux) sendCommands(target string, commands []config.Command, path string) error {
+	for cIndex, c := range commands {
+		commandPath := fmt.Sprintf("%s.commands[%d]", path, cIndex)
+
+		if c.Delay != "" {
+			delay, err := time.ParseDuration(c.Delay)
+			if err != nil {
+				return fmt.Errorf("%s.delay: %w", commandPath, err)
+			}
+
+			err = gmux.tmux.Flush()
+			if err != nil {
+				return withConfigPath(err, commandPath)
+			}
+			gmux.wait(delay)
+		}
+
+		// Text is typed as is, tmux would take a word like Escape as a key.
+		// Enter is a key name, it is sent on its own.
+		at := gmux.tmux.At(commandPath)
+		var err error
+		switch {
+		case c.Text != "":
+			err = at.SendLiteral(target, c.Text)
+		case len(c.Keys) > 0 && c.Literal:
+			err = at.SendLiteral(target, c.Keys...)
+		case len(c.Keys) > 0:
+			err = at.SendKeys(target, c.Keys...)
+		}
+		if err == nil && c.PressEnter() {
+			err = at.SendKeys(target, "Enter")
+		}
+		if err != nil {
+			return withConfigPath(err, commandPath)
+		}
+	}
+
+	return nil
+}
+
+// setOptions sets the tmux options on the target in the scope, sorted by name.
+func (gmux Gmux) setOptions(target string, scope string, options map[string]string) error {
+	for _, name := range sortedKeys(options) {
+		err := gmux.tmux.SetOption(target, scope, name, options[name])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sessionOptions returns the session options of the config options,
+// including user options and the options gmux doesn't know.
+func sessionOptions(options map[string]string) map[string]string {
+	session := map[string]string{}
+	for name, value := range options {
+		if tmux.IsSessionOption(name) || !tmux.IsWindowOption(name) {
+			session[name] = value
+		}
+	}
+
+	return session
+}
+
+// windowOptions returns the window options of the config options,
+// which every window starts with.
+func windowOptions(options map[string]string) map[string]string {
+	window := map[string]string{}
+	for name, value := range options {
+		if tmux.IsWindowOption(name) && !tmux.IsUserOption(name) {
+			window[name] = value
+		}
+	}
+
+	return window
+}
+
+// focusTargets returns the window and the pane to land in after start.
+// The window is startup_window, by name or tmux index, the window with focus
+// or the first started one. The pane is startup_pane, by name or tmux index,
+// or empty: panes with focus are selected as their windows start.
+func focusTargets(conf config.Config, started map[int]*paneSplitter, sessionName string) (string, string) {
+	var window *paneSplitter
+	windowTarget := ""
+	first := -1
+	for wIndex, w := range conf.Windows {
+		splitter, ok := started[wIndex]
+		if !ok {
+			continue
+		}
+
+		if first < 0 {
+			first = wIndex
+		}
+
+		if conf.StartupWindow != "" && w.Name == conf.StartupWindow {
+			window = splitter
+			break
+		}
+
+		if conf.StartupWindow == "" && w.Focus && window == nil {
+			window = splitter
+		}
+	}
+
+	switch {
+	case window != nil:
+		windowTarget = window.window
+	case conf.StartupWindow != "":
+		windowTarget = sessionName + conf.StartupWindow
+	default:
+		window = started[first]
+		windowTarget = window.window
+	}
+
+	if conf.StartupPane != "" {
+		if window != nil {
+			if pane, ok := window.named[conf.StartupPane]; ok {
+				return windowTarget, pane
+			}
+		}
+
+		return windowTarget, windowTarget + "." + conf.StartupPane
+	}
+
+	return windowTarget, ""
+}
+
+// hasPaneTree reports whether any of the panes sets a size,
+// splits a named pane or has nested panes.
+func hasPaneTree(panes []config.Pane) bool {
+	for _, p := range panes {
+		if p.Size != "" || p.SplitFrom != "" || len(p.Panes) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// paneSplitter creates the panes of a window.
+type paneSplitter struct {
+	gmux   Gmux
+	window string
+	// Pane ids by name, the window pane by the window name.
+	named   map[string]string
+	created int
+	// Panes are rebalanced to tiled after so many splits, never if 0.
+	rebalanceThreshold int
+	// Id of the pane with focus, empty if none has it.
+	focused string
+	// waitForShell waits for the prompt in every pane, not only in the panes with wait_for_shell.
+	waitForShell bool
+}
+
+// split creates the panes in the config order. The first pane splits parent,
+// every next one the pane before it, unless split_from names another pane.
+// Nested panes split the pane they are nested in. ownEnv is the env
+// the panes inherit from the panes they are nested in, without the window env.
+func (s *paneSplitter) split(parent string, panes []config.Pane, path string, root string, env map[string]string, ownEnv map[string]string) error {
+	last := parent
+	for pIndex, p := range panes {
+		panePath := fmt.Sprintf("%s.panes[%d]", path, pIndex)
+
+		target := last
+		if p.SplitFrom != "" {
+			named, ok := s.named[p.SplitFrom]
+			if !ok {
+				return &config.ValidationError{Path: panePath + ".split_from", Message: fmt.Sprintf("there is no pane %q before this one", p.SplitFrom)}
+			}
+			target = named
+		}
+
+		paneRoot := configRoot(root, p.Root)
+		paneEnv, err := layerEnv(env, p.Env)
+		if err != nil {
+			return fmt.Errorf("%s.env: %w", panePath, err)
+		}
+
+		paneOwnEnv, err := layerEnv(ownEnv, p.Env)
+		if err != nil {
+			return fmt.Errorf("%s.env: %w", panePath, err)
+		}
+
+		newPane, err := s.gmux.tmux.SplitWindow(target, p.Type, p.Size, paneRoot, paneEnv, p.Run, p.RemainOnExit)
+		if err != nil {
+			return withConfigPath(err, panePath)
+		}
+		last = newPane
+		s.created++
+
+		if p.Name != "" {
+			s.named[p.Name] = newPane
+		}
+
+		if p.Focus {
+			s.focused = newPane
+		}
 
+		err = s.gmux.tmux.At(panePath+".env").RecordEnv(newPane, tmux.PaneOption, paneOwnEnv)
+		if err != nil {
+			return withConfigPath(err, panePath+".env")
+		}
+
+		if p.Title != "" {
+			err = s.gmux.tmux.At(panePath+".title").SetPaneTitle(newPane, p.Title)
+			if err != nil {
+				return withConfigPath(err, panePath+".title")
+			}
+		}
+
+		err = s.gmux.at(panePath+".options").setOptions(newPane, tmux.PaneOption, p.Options)
+		if err != nil {
+			return withConfigPath(err, panePath+".options")
+		}
+
+		if (s.waitForShell || p.WaitForShell) && p.Run == "" && len(p.Commands) > 0 {
+			err = s.gmux.waitForShell(newPane)
+			if err != nil {
+				return withConfigPath(err, panePath+".wait_for_shell")
+			}
+		}
+
+		err = s.gmux.sendCommands(s.window+"."+newPane, p.Commands, panePath)
+		if err != nil {
+			return err
+		}
+
+		if s.rebalanceThreshold > 0 && s.created >= s.rebalanceThreshold {
+			_, err = s.gmux.tmux.At(panePath).SelectLayout(s.window, tmux.Tiled)
+			if err != nil {
+				return withConfigPath(err, panePath)
+			}
+		}
+
+		err = s.split(newPane, p.Panes, panePath, paneRoot, paneEnv, paneOwnEnv)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (gmux Gmux) GetConfigFromSession(options Options, context Context) (config.Config, error) {
 	tmuxSession, err := gmux.tmux.SessionName()
 	if err != nil {
 		return config.Config{}, err
 	}
+
+	conf, err := gmux.sessionConfig(options.Project)
+	if err != nil {
+		return config.Config{}, err
+	}
 	conf.Session = tmuxSession
 
-	tmuxWindows, err := gmux.tmux.ListWindows(options.Project)
+	return conf, nil
+}
+
+// sessionConfig returns the config of the windows and panes of the target session.
+func (gmux Gmux) sessionConfig(target string) (config.Config, error) {
+	conf := config.Config{}
+
+	var err error
+	conf.Options, err = gmux.showOptions(target, tmux.SessionOption)
 	if err != nil {
 		return config.Config{}, err
 	}
 
-	for _, w := range tmuxWindows {
-		tmuxPanes, err := gmux.tmux.ListPanes(options.Project + ":" + w.Id)
+	tmuxWindows, err := gmux.tmux.ListWindows(target)
+	if err != nil {
+		return config.Config{}, err
+	}
+
+	for wIndex, w := range tmuxWindows {
+		windowTarget := target + ":" + w.Id
+		tmuxPanes, err := gmux.tmux.ListPanes(windowTarget)
+		if err != nil {
+			return config.Config{}, err
+		}
+
+		windowOptions, err := gmux.showOptions(windowTarget, tmux.WindowOption)
 		if err != nil {
 			return config.Config{}, err
 		}
 
-		panes := []config.Pane{}
+		// The first pane is the window itself, Start splits it into the rest.
+		// Like the first window, it is active unless another one has focus.
+		windowRoot := w.Root
+		var windowTitle string
+		if len(tmuxPanes) > 0 {
+			windowRoot = tmuxPanes[0].Root
+			windowTitle = tmuxPanes[0].Title
+			tmuxPanes = tmuxPanes[1:]
+		}
+
+		var panes []config.Pane
 		for _, p := range tmuxPanes {
 			root := p.Root
-			if root == w.Root {
+			if root == windowRoot {
 				root = ""
 			}
+
+			// Pane options fall back to window options,
+			// so a pane without its own env reports the window one.
+			env := p.Env
+			if reflect.DeepEqual(env, w.Env) {
+				env = nil
+			}
+
+			paneOptions, err := gmux.showOptions(p.Id, tmux.PaneOption)
+			if err != nil {
+				return config.Config{}, err
+			}
+
 			panes = append(panes, config.Pane{
-				Root: root,
+				Root:    root,
+				Env:     env,
+				Focus:   p.Active,
+				Title:   p.Title,
+				Options: paneOptions,
 			})
 		}
 
 		conf.Windows = append(conf.Windows, config.Window{
-			Name:   w.Name,
-			Layout: w.Layout,
-			Root:   w.Root,
-			Panes:  panes,
+			Name:    w.Name,
+			Layout:  w.Layout,
+			Root:    windowRoot,
+			Env:     w.Env,
+			Panes:   panes,
+			Focus:   w.Active && wIndex > 0,
+			Options: windowOptions,
+			Title:   windowTitle,
 		})
 	}
 
 	return conf, nil
 }
+
+// showOptions returns the options set on the target, nil if there are none.
+func (gmux Gmux) showOptions(target string, scope string) (map[string]string, error) {
+	options, err := gmux.tmux.ShowOptions(target, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// tmux turns automatic-rename off for the windows gmux names.
+	if scope == tmux.WindowOption && options["automatic-rename"] == "off" {
+		delete(options, "automatic-rename")
+	}
+
+	if len(options) == 0 {
+		return nil, nil
+	}
+
+	return options, nil
+}
@@ -1,87 +1,87 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aaqaishtyaq/gmux/config"
+	"github.com/aaqaishtyaq/gmux/executor"
 	"github.com/aaqaishtyaq/gmux/tmux"
+	"github.com/aaqaishtyaq/gmux/tmux/tmuxtest"
 )
 
 var testTable = map[string]struct {
-	config           config.Config
-	options          Options
-	context          Context
-	startCommands    []string
-	stopCommands     []string
-	commanderOutputs []string
+	config  config.Config
+	options Options
+	context Context
+	// Prepares the tmux server before the session is started.
+	setup func(server *tmuxtest.Server)
+	// Commands run outside of tmux by start and stop.
+	startExternal []string
+	stopExternal  []string
+	// Checks the tmux server state after start.
+	started func(t *testing.T, server *tmuxtest.Server)
+	// Checks the tmux server state after stop.
+	stopped func(t *testing.T, server *tmuxtest.Server)
 }{
 	"test with 1 window": {
-		config.Config{
+		config: config.Config{
 			Session:     "test-session",
 			Root:        "~/root",
-			BeforeStart: []string{"command1", "command2"},
+			BeforeStart: config.Hook{Commands: []string{"command1", "command2"}},
 			Windows: []config.Window{
 				{
 					Name:     "win1",
-					Commands: []string{"command1"},
+					Commands: config.Commands("command1"),
 				},
 			},
 		},
-		Options{},
-		Context{},
-		[]string{
-			"tmux has-session -t test-session:",
+		startExternal: []string{
 			"/bin/sh -c command1",
 			"/bin/sh -c command2",
-			"tmux new -Pd -s test-session -n gomux_def -c gmux/root",
-			"tmux neww -Pd -t test-session: -c gmux/root -F #{window_id} -n win1",
-			"tmux send-keys -t win1 command1 Enter",
-			"tmux select-layout -t win1 even-horizontal",
-			"tmux kill-window -t test-session:gomux_def",
-			"tmux move-window -r -s test-session: -t test-session:",
-			"tmux attach -d -t test-session:win1",
-		},
-		[]string{
-			"tmux kill-session -t test-session",
-		},
-		[]string{"test-session", "win1"},
+		},
+		started: func(t *testing.T, server *tmuxtest.Server) {
+			session := expectSession(t, server, "test-session", "win1")
+			win1 := session.Window("win1")
+			expectEqual(t, "gmux/root", win1.Pane(0).Root)
+			expectEqual(t, []string{"-l command1", "Enter"}, win1.Pane(0).Keys)
+			expectEqual(t, tmux.EvenHorizontal, win1.Layout)
+			expectEqual(t, "test-session", server.Client)
+		},
+		stopped: expectNoSession,
 	},
 	"test with 1 window and Detach: true": {
-		config.Config{
+		config: config.Config{
 			Session:     "test-session",
 			Root:        "root",
-			BeforeStart: []string{"command1", "command2"},
+			BeforeStart: config.Hook{Commands: []string{"command1", "command2"}},
 			Windows: []config.Window{
 				{
 					Name: "win1",
 				},
 			},
 		},
-		Options{
+		options: Options{
 			Detach: true,
 		},
-		Context{},
-		[]string{
-			"tmux has-session -t test-session:",
+		startExternal: []string{
 			"/bin/sh -c command1",
 			"/bin/sh -c command2",
-			"tmux new -Pd -s test-session -n gomux_def -c root",
-			"tmux neww -Pd -t test-session: -c root -F #{window_id} -n win1",
-			"tmux select-layout -t xyz even-horizontal",
-			"tmux kill-window -t test-session:gomux_def",
-			"tmux move-window -r -s test-session: -t test-session:",
 		},
-		[]string{
-			"tmux kill-session -t test-session",
+		started: func(t *testing.T, server *tmuxtest.Server) {
+			expectSession(t, server, "test-session", "win1")
+			expectEqual(t, "", server.Client)
 		},
-		[]string{"xyz"},
+		stopped: expectNoSession,
 	},
 	"test with multiple windows and panes": {
-		config.Config{
+		config: config.Config{
 			Session: "test-session",
 			Root:    "root",
 			Windows: []config.Window{
@@ -92,7 +92,7 @@ var testTable = map[string]struct {
 					Panes: []config.Pane{
 						{
 							Type:     "horizontal",
-							Commands: []string{"command1"},
+							Commands: config.Commands("command1"),
 						},
 					},
 				},
@@ -102,33 +102,196 @@ var testTable = map[string]struct {
 					Layout: "tiled",
 				},
 			},
-			Stop: []string{
+			Stop: config.Hook{Commands: []string{
 				"stop1",
 				"stop2 -d --foo=bar",
-			},
+			}},
 		},
-		Options{},
-		Context{},
-		[]string{
-			"tmux has-session -t test-session:",
-			"tmux new -Pd -s test-session -n gomux_def -c root",
-			"tmux neww -Pd -t test-session: -c root -F #{window_id} -n win1",
-			"tmux split-window -Pd -h -t win1 -c root -F #{pane_id}",
-			"tmux send-keys -t win1.1 command1 Enter",
-			"tmux select-layout -t win1 main-horizontal",
-			"tmux kill-window -t test-session:gomux_def",
-			"tmux move-window -r -s test-session: -t test-session:",
-			"tmux attach -d -t test-session:win1",
-		},
-		[]string{
+		started: func(t *testing.T, server *tmuxtest.Server) {
+			session := expectSession(t, server, "test-session", "win1")
+			win1 := session.Window("win1")
+			expectEqual(t, 2, len(win1.Panes))
+			expectEqual(t, []string(nil), win1.Pane(0).Keys)
+			expectEqual(t, []string{"-l command1", "Enter"}, win1.Pane(1).Keys)
+			expectEqual(t, tmux.MainHorizontal, win1.Layout)
+		},
+		stopExternal: []string{
 			"/bin/sh -c stop1",
 			"/bin/sh -c stop2 -d --foo=bar",
-			"tmux kill-session -t test-session",
 		},
-		[]string{"test-session", "test-session", "win1", "1"},
+		stopped: expectNoSession,
+	},
+	"test with a raw layout": {
+		config: config.Config{
+			Session: "test-session",
+			Root:    "root",
+			Windows: []config.Window{
+				{
+					Name:   "win1",
+					Layout: "5719,158x40,0,0{79x40,0,0,0,78x40,80,0[78x20,80,0,1,78x19,80,21,2]}",
+					Panes:  []config.Pane{{}, {}},
+				},
+			},
+		},
+		started: func(t *testing.T, server *tmuxtest.Server) {
+			session := expectSession(t, server, "test-session", "win1")
+			expectEqual(t, "5719,158x40,0,0{79x40,0,0,0,78x40,80,0[78x20,80,0,1,78x19,80,21,2]}", session.Window("win1").Layout)
+		},
+		stopped: expectNoSession,
+	},
+	"test with a pane tree": {
+		config: config.Config{
+			Session: "test-session",
+			Root:    "root",
+			Windows: []config.Window{
+				{
+					Name: "code",
+					Panes: []config.Pane{
+						{
+							Name: "right",
+							Type: "horizontal",
+							Size: "40%",
+							Panes: []config.Pane{
+								{Type: "vertical", Size: "10", Commands: config.Commands("tail -f log")},
+							},
+						},
+						{SplitFrom: "code", Type: "vertical", Size: "5"},
+					},
+				},
+			},
+		},
+		started: func(t *testing.T, server *tmuxtest.Server) {
+			session := expectSession(t, server, "test-session", "code")
+			code := session.Window("code")
+			expectEqual(t, 4, len(code.Panes))
+
+			// Every split goes right after the split pane.
+			editor, bottom, right, logs := code.Pane(0), code.Pane(1), code.Pane(2), code.Pane(3)
+			expectEqual(t, []interface{}{editor.Id, true, "40%"}, []interface{}{right.Split, right.Horizontal, right.Size})
+			expectEqual(t, []interface{}{right.Id, false, "10"}, []interface{}{logs.Split, logs.Horizontal, logs.Size})
+			expectEqual(t, []string{"-l tail -f log", "Enter"}, logs.Keys)
+			expectEqual(t, []interface{}{editor.Id, false, "5"}, []interface{}{bottom.Split, bottom.Horizontal, bottom.Size})
+			expectEqual(t, "", code.Layout)
+		},
+		stopped: expectNoSession,
+	},
+	"test with titles and options": {
+		config: config.Config{
+			Session: "test-session",
+			Root:    "root",
+			Options: map[string]string{"mouse": "on", "pane-border-status": "top", "@project": "test"},
+			Windows: []config.Window{
+				{
+					Name:    "web",
+					Title:   "shell",
+					Options: map[string]string{"pane-border-status": "bottom", "monitor-activity": "on"},
+					Panes: []config.Pane{
+						{Title: "rails", Options: map[string]string{"remain-on-exit": "on"}},
+						{Title: "sidekiq"},
+					},
+				},
+			},
+		},
+		started: func(t *testing.T, server *tmuxtest.Server) {
+			session := expectSession(t, server, "test-session", "web")
+			expectEqual(t, map[string]string{"mouse": "on", "@project": "test"}, session.Options)
+
+			web := session.Window("web")
+			expectEqual(t, map[string]string{"pane-border-status": "bottom", "monitor-activity": "on"}, web.Options)
+			expectEqual(t, []string{"shell", "rails", "sidekiq"}, []string{web.Pane(0).Title, web.Pane(1).Title, web.Pane(2).Title})
+			expectEqual(t, map[string]string{"remain-on-exit": "on"}, web.Pane(1).Options)
+		},
+		stopped: expectNoSession,
+	},
+	"test with run": {
+		config: config.Config{
+			Session: "test-session",
+			Root:    "root",
+			Windows: []config.Window{
+				{
+					Name:         "servers",
+					Run:          "bin/rails server",
+					RemainOnExit: true,
+					Panes: []config.Pane{
+						{Run: "bundle exec sidekiq", Commands: config.Commands("y")},
+						{Commands: config.Commands("tail -f log/development.log")},
+					},
+				},
+			},
+		},
+		started: func(t *testing.T, server *tmuxtest.Server) {
+			servers := expectSession(t, server, "test-session", "servers").Window("servers")
+			expectEqual(t, []string{"bin/rails server", "bundle exec sidekiq", ""}, []string{servers.Pane(0).Run, servers.Pane(1).Run, servers.Pane(2).Run})
+			expectEqual(t, map[string]string{"remain-on-exit": "on"}, servers.Pane(0).Options)
+			expectEqual(t, map[string]string{}, servers.Pane(1).Options)
+			expectEqual(t, []string{"-l y", "Enter"}, servers.Pane(1).Keys)
+		},
+		stopped: expectNoSession,
+	},
+	"test with focus": {
+		config: config.Config{
+			Session: "test-session",
+			Root:    "root",
+			Windows: []config.Window{
+				{Name: "shell"},
+				{
+					Name:  "code",
+					Focus: true,
+					Panes: []config.Pane{{Focus: true}, {}},
+				},
+			},
+		},
+		started: func(t *testing.T, server *tmuxtest.Server) {
+			session := expectSession(t, server, "test-session", "shell", "code")
+			code := session.Window("code")
+			expectEqual(t, code, session.ActiveWindow())
+			expectEqual(t, code.Pane(1), code.ActivePane())
+		},
+		stopped: expectNoSession,
+	},
+	"test with startup window and pane": {
+		config: config.Config{
+			Session:       "test-session",
+			Root:          "root",
+			StartupWindow: "code",
+			StartupPane:   "logs",
+			Windows: []config.Window{
+				{Name: "shell", Focus: true},
+				{
+					Name:  "code",
+					Panes: []config.Pane{{Focus: true}, {Name: "logs"}},
+				},
+			},
+		},
+		started: func(t *testing.T, server *tmuxtest.Server) {
+			session := expectSession(t, server, "test-session", "shell", "code")
+			code := session.Window("code")
+			expectEqual(t, code, session.ActiveWindow())
+			expectEqual(t, code.Pane(2), code.ActivePane())
+		},
+		stopped: expectNoSession,
+	},
+	"test with startup window and pane indexes": {
+		config: config.Config{
+			Session:       "test-session",
+			Root:          "root",
+			StartupWindow: "1",
+			StartupPane:   "0",
+			Windows: []config.Window{
+				{Name: "shell"},
+				{Name: "code", Panes: []config.Pane{{}}},
+			},
+		},
+		started: func(t *testing.T, server *tmuxtest.Server) {
+			session := expectSession(t, server, "test-session", "shell", "code")
+			code := session.Window("code")
+			expectEqual(t, code, session.ActiveWindow())
+			expectEqual(t, code.Pane(0), code.ActivePane())
+		},
+		stopped: expectNoSession,
 	},
 	"test start windows from option's Windows parameter": {
-		config.Config{
+		config: config.Config{
 			Session: "test-session",
 			Root:    "root",
 			Windows: []config.Window{
@@ -140,163 +303,291 @@ var testTable = map[string]struct {
 					Name:   "win2",
 					Manual: true,
 				},
+				{
+					Name:   "win3",
+					Manual: true,
+				},
 			},
 		},
-		Options{
-			Windows: []string{"win2"},
+		options: Options{
+			Windows: []string{"win2", "win3"},
 		},
-		Context{},
-		[]string{
-			"tmux has-session -t test-session:",
-			"tmux new -Pd -s test-session -n gomux_def -c root",
-			"tmux neww -Pd -t test-session: -c root -F #{window_id} -n win2",
-			"tmux select-layout -t xyz even-horizontal",
-			"tmux kill-window -t test-session:gomux_def",
-			"tmux move-window -r -s test-session: -t test-session:",
+		started: func(t *testing.T, server *tmuxtest.Server) {
+			expectSession(t, server, "test-session", "win2", "win3")
 		},
-		[]string{
-			"tmux kill-window -t test-session:win2",
-		},
-		[]string{"xyz"},
+		stopped: expectNoSession,
 	},
 	"test attach to the existing session": {
-		config.Config{
+		config: config.Config{
 			Session: "test-session",
 			Root:    "root",
 			Windows: []config.Window{
 				{Name: "win1"},
 			},
 		},
-		Options{},
-		Context{},
-		[]string{
-			"tmux has-session -t test-session:",
-			"tmux attach -d -t test-session:",
+		setup: func(server *tmuxtest.Server) {
+			server.AddSession("test-session", "root")
 		},
-		[]string{
-			"tmux kill-session -t test-session",
+		started: func(t *testing.T, server *tmuxtest.Server) {
+			expectSession(t, server, "test-session", "sh")
+			expectEqual(t, "test-session", server.Client)
 		},
-		[]string{""},
+		stopped: expectNoSession,
 	},
 	"test start a new session from another tmux session": {
-		config.Config{
+		config: config.Config{
 			Session: "test-session",
 			Root:    "root",
+			Windows: []config.Window{
+				{Name: "win1"},
+			},
 		},
-		Options{Attach: false},
-		Context{InsideTmuxSession: true},
-		[]string{
-			"tmux has-session -t test-session:",
-			"tmux new -Pd -s test-session -n gomux_def -c root",
-			"tmux kill-window -t test-session:gomux_def",
-			"tmux move-window -r -s test-session: -t test-session:",
-		},
-		[]string{
-			"tmux kill-session -t test-session",
+		options: Options{Attach: false},
+		context: Context{InsideTmuxSession: true},
+		started: func(t *testing.T, server *tmuxtest.Server) {
+			expectSession(t, server, "test-session", "win1")
+			expectEqual(t, "", server.Client)
 		},
-		[]string{"xyz"},
+		stopped: expectNoSession,
 	},
 	"test switch a client from another tmux session": {
-		config.Config{
+		config: config.Config{
 			Session: "test-session",
 			Root:    "root",
 			Windows: []config.Window{
 				{Name: "win1"},
 			},
 		},
-		Options{Attach: true},
-		Context{InsideTmuxSession: true},
-		[]string{
-			"tmux has-session -t test-session:",
-			"tmux switch-client -t test-session:",
+		options: Options{Attach: true},
+		context: Context{InsideTmuxSession: true},
+		setup: func(server *tmuxtest.Server) {
+			server.AddSession("test-session", "root")
 		},
-		[]string{
-			"tmux kill-session -t test-session",
+		started: func(t *testing.T, server *tmuxtest.Server) {
+			expectSession(t, server, "test-session", "sh")
+			expectEqual(t, "test-session", server.Client)
 		},
-		[]string{""},
+		stopped: expectNoSession,
 	},
 	"test create new windows in current session": {
-		config.Config{
+		config: config.Config{
 			Session: "test-session",
 			Root:    "root",
 			Windows: []config.Window{
 				{Name: "win1"},
 			},
 		},
-		Options{
+		options: Options{
 			InsideCurrentSession: true,
 		},
-		Context{InsideTmuxSession: true},
-		[]string{
-			"tmux has-session -t test-session:",
-			"tmux neww -Pd -t test-session: -c root -F #{window_id} -n win1",
-			"tmux select-layout -t  even-horizontal",
+		context: Context{InsideTmuxSession: true},
+		setup: func(server *tmuxtest.Server) {
+			server.AddSession("test-session", "root")
 		},
-		[]string{
-			"tmux kill-session -t test-session",
+		started: func(t *testing.T, server *tmuxtest.Server) {
+			expectSession(t, server, "test-session", "sh", "win1")
+			expectEqual(t, "", server.Client)
 		},
-		[]string{""},
+		stopped: expectNoSession,
 	},
 }
 
-type MockExecutor struct {
-	Commands []string
-	Outputs  []string
+func expectEqual(t *testing.T, expected interface{}, actual interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %#v, got %#v", expected, actual)
+	}
 }
 
-func (c *MockExecutor) Exec(cmd *exec.Cmd) (string, error) {
-	c.Commands = append(c.Commands, strings.Join(cmd.Args, " "))
+// expectSession fails the test unless the session exists with exactly the windows.
+func expectSession(t *testing.T, server *tmuxtest.Server, name string, windows ...string) *tmuxtest.Session {
+	t.Helper()
 
-	output := ""
-	if len(c.Outputs) > 1 {
-		output, c.Outputs = c.Outputs[0], c.Outputs[1:]
-	} else if len(c.Outputs) == 1 {
-		output = c.Outputs[0]
+	session := server.Session(name)
+	if session == nil {
+		t.Fatalf("expected session %q to exist", name)
 	}
 
-	return output, nil
+	if !reflect.DeepEqual(windows, session.WindowNames()) {
+		t.Fatalf("expected windows %q, got %q", windows, session.WindowNames())
+	}
+
+	return session
+}
+
+func expectNoSession(t *testing.T, server *tmuxtest.Server) {
+	t.Helper()
+
+	if session := server.Session("test-session"); session != nil {
+		t.Errorf("expected session to be stopped, got windows %q", session.WindowNames())
+	}
 }
 
-func (c *MockExecutor) ExecQuiet(cmd *exec.Cmd) error {
-	c.Commands = append(c.Commands, strings.Join(cmd.Args, " "))
-	return nil
+func newTestGmux(server *tmuxtest.Server) Gmux {
+	return Gmux{tmux: tmux.Tmux{Executor: server}, executor: server}
 }
 
 func TestStartStopSession(t *testing.T) {
 	os.Setenv("HOME", "gmux") // Needed for testing ExpandPath function
 
 	for testDescription, params := range testTable {
+		server := tmuxtest.NewServer()
+		if params.setup != nil {
+			params.setup(server)
+		}
+		gmux := newTestGmux(server)
 
 		t.Run("start session: "+testDescription, func(t *testing.T) {
-			executor := &MockExecutor{[]string{}, params.commanderOutputs}
-			tmux := tmux.Tmux{Executor: executor}
-			gmux := Gmux{tmux, executor}
-
 			err := gmux.Start(params.config, params.options, params.context)
 			if err != nil {
 				t.Fatalf("error %v", err)
 			}
 
-			if !reflect.DeepEqual(params.startCommands, executor.Commands) {
-				t.Errorf("expected\n%s\ngot\n%s", strings.Join(params.startCommands, "\n"), strings.Join(executor.Commands, "\n"))
+			if !reflect.DeepEqual(params.startExternal, server.External) {
+				t.Errorf("expected\n%s\ngot\n%s", strings.Join(params.startExternal, "\n"), strings.Join(server.External, "\n"))
 			}
+
+			params.started(t, server)
 		})
 
 		t.Run("stop session: "+testDescription, func(t *testing.T) {
-			executor := &MockExecutor{[]string{}, params.commanderOutputs}
-			tmux := tmux.Tmux{Executor: executor}
-			gmux := Gmux{tmux, executor}
+			server.External = nil
 
-			err := gmux.Stop(params.config, params.options, params.context)
+			options := params.options
+			options.Windows = nil
+
+			err := gmux.Stop(params.config, options, params.context)
 			if err != nil {
 				t.Fatalf("error %v", err)
 			}
 
-			if !reflect.DeepEqual(params.stopCommands, executor.Commands) {
-				t.Errorf("expected\n%s\ngot\n%s", strings.Join(params.stopCommands, "\n"), strings.Join(executor.Commands, "\n"))
+			if !reflect.DeepEqual(params.stopExternal, server.External) {
+				t.Errorf("expected\n%s\ngot\n%s", strings.Join(params.stopExternal, "\n"), strings.Join(server.External, "\n"))
 			}
+
+			params.stopped(t, server)
 		})
+	}
+}
+
+func TestStopWindows(t *testing.T) {
+	server := tmuxtest.NewServer()
+	session := server.AddSession("test-session", "root")
+	server.AddWindow(session, "win1", "root")
+	server.AddWindow(session, "win2", "root")
+	gmux := newTestGmux(server)
 
+	conf := config.Config{
+		Session: "test-session",
+		Stop:    config.Hook{Commands: []string{"stop1"}},
+	}
+
+	err := gmux.Stop(conf, Options{Windows: []string{"win1", "sh"}}, Context{})
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+
+	expectSession(t, server, "test-session", "win2")
+	expectEqual(t, []string(nil), server.External)
+}
+
+func TestStopBrokenEnvFile(t *testing.T) {
+	server := tmuxtest.NewServer()
+	server.AddSession("test-session", "root")
+	out := &bytes.Buffer{}
+	gmux := newTestGmux(server)
+	gmux.output = out
+
+	conf := config.Config{
+		Session: "test-session",
+		Root:    t.TempDir(),
+		EnvFile: "missing.env",
+		Stop:    config.Hook{Commands: []string{"stop1"}},
+	}
+
+	err := gmux.Stop(conf, Options{}, Context{})
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+
+	if server.Session("test-session") != nil {
+		t.Error("expected the session to be stopped")
+	}
+	expectEqual(t, []string(nil), server.External)
+	if !strings.HasPrefix(out.String(), "Skipping the stop hook: env_file:") {
+		t.Errorf("expected a warning for the hook, got %q", out.String())
+	}
+}
+
+func TestStartExistingSessionBrokenEnvFile(t *testing.T) {
+	server := tmuxtest.NewServer()
+	server.AddSession("test-session", "root")
+	gmux := newTestGmux(server)
+
+	conf := config.Config{Session: "test-session", Root: t.TempDir(), EnvFile: "missing.env"}
+
+	err := gmux.Start(conf, Options{}, Context{InsideTmuxSession: true})
+	if err != nil {
+		t.Fatalf("expected to switch to the running session, got %v", err)
+	}
+}
+
+func TestStartBadConfigKeepsRunningSession(t *testing.T) {
+	tests := map[string]struct {
+		config  config.Config
+		options Options
+	}{
+		"unknown layout": {
+			config:  config.Config{Session: "test-session", Windows: []config.Window{{Name: "win", Layout: "diagonal"}}},
+			options: Options{InsideCurrentSession: true},
+		},
+		"session option on a window": {
+			config:  config.Config{Session: "test-session", Windows: []config.Window{{Name: "win", Options: map[string]string{"status": "off"}}}},
+			options: Options{Windows: []string{"win"}},
+		},
+		"unknown split_from": {
+			config:  config.Config{Session: "test-session", Windows: []config.Window{{Name: "win", Panes: []config.Pane{{SplitFrom: "missing"}}}}},
+			options: Options{InsideCurrentSession: true},
+		},
+		"broken env file": {
+			config:  config.Config{Session: "test-session", Root: t.TempDir(), EnvFile: "missing.env", Windows: []config.Window{{Name: "win"}}},
+			options: Options{InsideCurrentSession: true},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := tmuxtest.NewServer()
+			server.AddSession("test-session", "root")
+			gmux := newTestGmux(server)
+
+			err := gmux.rollBack(test.config, test.options, Context{}, gmux.Start(test.config, test.options, Context{}))
+			if !errors.Is(err, ErrNothingStarted) {
+				t.Fatalf("expected ErrNothingStarted, got %v", err)
+			}
+
+			if server.Session("test-session") == nil {
+				t.Errorf("expected the running session to survive, got %q", server.Commands)
+			}
+		})
+	}
+}
+
+func TestStartRollsBackStartedSession(t *testing.T) {
+	conf := config.Config{Session: "test-session", Root: "root", Windows: []config.Window{{Name: "win"}}}
+
+	server := tmuxtest.NewServer()
+	server.FailOn("tmux neww", "create window failed")
+	gmux := newTestGmux(server)
+
+	err := gmux.rollBack(conf, Options{Detach: true}, Context{}, gmux.Start(conf, Options{Detach: true}, Context{}))
+	if err == nil || errors.Is(err, ErrNothingStarted) {
+		t.Fatalf("expected a start error, got %v", err)
+	}
+
+	if server.Session("test-session") != nil {
+		t.Errorf("expected the started session to be stopped")
 	}
 }
 
@@ -306,10 +597,9 @@ func TestPrintCurrentSession(t *testing.T) {
 		Windows: []config.Window{
 			{
 				Name:   "win1",
-				Root:   "root",
-				Layout: "layout",
+				Root:   "/root",
+				Layout: "main-vertical",
 				Panes: []config.Pane{
-					{},
 					{
 						Root: "/tmp",
 					},
@@ -318,16 +608,27 @@ func TestPrintCurrentSession(t *testing.T) {
 		},
 	}
 
-	executor := &MockExecutor{[]string{}, []string{
-		"session_name",
-		"id1;win1;layout;root",
-		"root\n/tmp",
-	}}
-	tmux := tmux.Tmux{Executor: executor}
+	server := tmuxtest.NewServer()
+	gmux := newTestGmux(server)
 
-	gmux := Gmux{tmux, executor}
+	err := gmux.Start(config.Config{
+		Session: "session_name",
+		Root:    "/root",
+		Windows: []config.Window{
+			{
+				Name:   "win1",
+				Layout: "main-vertical",
+				Panes: []config.Pane{
+					{Root: "/tmp"},
+				},
+			},
+		},
+	}, Options{}, Context{})
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
 
-	actualConfig, err := gmux.GetConfigFromSession(Options{Project: "test"}, Context{})
+	actualConfig, err := gmux.GetConfigFromSession(Options{Project: "session_name"}, Context{})
 	if err != nil {
 		t.Fatalf("error %v", err)
 	}
@@ -336,3 +637,409 @@ func TestPrintCurrentSession(t *testing.T) {
 		t.Errorf("expected %v, got %v", expectedConfig, actualConfig)
 	}
 }
+
+func TestPrintFocus(t *testing.T) {
+	server := tmuxtest.NewServer()
+	gmux := newTestGmux(server)
+
+	err := gmux.Start(config.Config{
+		Session: "session_name",
+		Root:    "/root",
+		Windows: []config.Window{
+			{Name: "win1", Panes: []config.Pane{{}}},
+			{Name: "win2", Focus: true, Panes: []config.Pane{{}, {Focus: true}}},
+		},
+	}, Options{}, Context{})
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+
+	conf, err := gmux.GetConfigFromSession(Options{Project: "session_name"}, Context{})
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+
+	var focus []bool
+	for _, w := range conf.Windows {
+		focus = append(focus, w.Focus)
+		for _, p := range w.Panes {
+			focus = append(focus, p.Focus)
+		}
+	}
+
+	// The first window and the window panes are active by default.
+	expectEqual(t, []bool{false, false, true, false, true}, focus)
+}
+
+func TestPrintTitlesAndOptions(t *testing.T) {
+	server := tmuxtest.NewServer()
+	gmux := newTestGmux(server)
+
+	err := gmux.Start(config.Config{
+		Session: "session_name",
+		Root:    "/root",
+		Options: map[string]string{"mouse": "on"},
+		Windows: []config.Window{{
+			Name:    "win1",
+			Title:   "shell",
+			Options: map[string]string{"synchronize-panes": "on"},
+			Panes:   []config.Pane{{Title: "logs; errors", Options: map[string]string{"remain-on-exit": "on"}}},
+		}},
+	}, Options{}, Context{})
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+
+	conf, err := gmux.GetConfigFromSession(Options{Project: "session_name"}, Context{})
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+
+	expectEqual(t, map[string]string{"mouse": "on"}, conf.Options)
+	expectEqual(t, map[string]string{"synchronize-panes": "on"}, conf.Windows[0].Options)
+	expectEqual(t, "shell", conf.Windows[0].Title)
+	expectEqual(t, "logs; errors", conf.Windows[0].Panes[0].Title)
+	expectEqual(t, map[string]string{"remain-on-exit": "on"}, conf.Windows[0].Panes[0].Options)
+}
+
+func TestStartErrorConfigPath(t *testing.T) {
+	conf := config.Config{
+		Session: "test-session",
+		Root:    "root",
+		Windows: []config.Window{
+			{Name: "win1"},
+			{
+				Name: "win2",
+				Panes: []config.Pane{
+					{Type: "horizontal"},
+					{Type: "vertical"},
+				},
+			},
+		},
+	}
+
+	server := tmuxtest.NewServer()
+	server.FailOn("tmux split-window -Pd -v", "no space for new pane")
+	gmux := newTestGmux(server)
+
+	err := gmux.Start(conf, Options{Detach: true}, Context{})
+
+	var shellErr *executor.ShellError
+	if !errors.As(err, &shellErr) {
+		t.Fatalf("expected ShellError, got %v", err)
+	}
+
+	expectEqual(t, "windows[1].panes[1]", shellErr.ConfigPath)
+	expectEqual(t, "no space for new pane", shellErr.Stderr)
+}
+
+func TestStartUnknownSplitFrom(t *testing.T) {
+	conf := config.Config{
+		Session: "test-session",
+		Root:    "root",
+		Windows: []config.Window{{Name: "win", Panes: []config.Pane{{SplitFrom: "missing"}}}},
+	}
+
+	server := tmuxtest.NewServer()
+	gmux := newTestGmux(server)
+
+	err := gmux.Start(conf, Options{Detach: true}, Context{})
+
+	var validationErr *config.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+
+	expectEqual(t, "windows[0].panes[0].split_from", validationErr.Path)
+	for _, command := range server.Commands {
+		if strings.Contains(command, "split-window") {
+			t.Errorf("expected no split, got %q", command)
+		}
+	}
+}
+
+func TestStartQueuedErrorConfigPath(t *testing.T) {
+	conf := config.Config{
+		Session: "test-session",
+		Root:    "root",
+		Windows: []config.Window{
+			{
+				Name:     "win1",
+				Options:  map[string]string{"mode-keys": "vi"},
+				Commands: config.Commands("make", "make test"),
+				Layout:   tmux.Tiled,
+			},
+		},
+	}
+
+	server := tmuxtest.NewServer()
+	server.FailOn("tmux send-keys -l -t @1 -- make test", "not a terminal")
+	gmux := newTestGmux(server)
+	gmux.tmux.Queue = &tmux.Queue{}
+
+	err := gmux.Start(conf, Options{Detach: true}, Context{})
+
+	var shellErr *executor.ShellError
+	if !errors.As(err, &shellErr) {
+		t.Fatalf("expected ShellError, got %v", err)
+	}
+
+	expectEqual(t, "windows[0].commands[1]", shellErr.ConfigPath)
+	expectEqual(t, "not a terminal", shellErr.Stderr)
+	// The commands queued before the rejected one ran, the default window is still there.
+	expectEqual(t, "vi", server.Session("test-session").Windows[1].Options["mode-keys"])
+}
+
+func TestStartUnknownOptions(t *testing.T) {
+	tests := map[string]struct {
+		config   config.Config
+		failOn   string
+		expected string
+	}{
+		"session": {
+			config:   config.Config{Session: "test-session", Options: map[string]string{"extended-keys": "on"}, Windows: []config.Window{{Name: "win"}}},
+			failOn:   "tmux set-option -t test-session extended-keys",
+			expected: "options",
+		},
+		"window": {
+			config:   config.Config{Session: "test-session", Windows: []config.Window{{Name: "win", Options: map[string]string{"pane-scrollbars": "on"}}}},
+			failOn:   "tmux set-option -w -t @1 pane-scrollbars",
+			expected: "windows[0].options",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := tmuxtest.NewServer()
+			server.FailOn(test.failOn, "invalid option")
+			gmux := newTestGmux(server)
+			gmux.tmux.Queue = &tmux.Queue{}
+			// Newer than the options gmux knows, so they are left to tmux.
+			gmux.tmux.Version = tmux.Version{Major: 3, Minor: 6}
+
+			err := gmux.Start(test.config, Options{Detach: true}, Context{})
+
+			var shellErr *executor.ShellError
+			if !errors.As(err, &shellErr) {
+				t.Fatalf("expected tmux to reject the option, got %v", err)
+			}
+
+			expectEqual(t, test.expected, shellErr.ConfigPath)
+		})
+	}
+}
+
+func TestSessionEnv(t *testing.T) {
+	root := t.TempDir()
+	err := os.WriteFile(filepath.Join(root, ".env"), []byte("PORT=3000\nNODE_ENV=development\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := config.Config{
+		EnvFile: ".env",
+		Env:     map[string]string{"PORT": "4000"},
+	}
+
+	env, err := sessionEnv(conf, root)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	expected := map[string]string{"PORT": "4000", "NODE_ENV": "development"}
+	if !reflect.DeepEqual(expected, env) {
+		t.Errorf("expected %v, got %v", expected, env)
+	}
+
+	_, err = sessionEnv(config.Config{Env: map[string]string{"BAD NAME": "x"}}, root)
+	if err == nil {
+		t.Errorf("expected an error for an invalid variable name")
+	}
+
+	_, err = sessionEnv(config.Config{EnvFile: "missing.env"}, root)
+	if err == nil {
+		t.Errorf("expected an error for a missing env_file")
+	}
+}
+
+func TestStartWindowAndPaneEnv(t *testing.T) {
+	conf := config.Config{
+		Session: "test-session",
+		Root:    "/root",
+		Env:     map[string]string{"NODE_ENV": "development"},
+		Windows: []config.Window{
+			{
+				Name: "web",
+				Env:  map[string]string{"PORT": "3000"},
+				Panes: []config.Pane{
+					{
+						Type: "horizontal",
+						Env:  map[string]string{"PORT": "3001"},
+					},
+				},
+			},
+		},
+	}
+
+	server := tmuxtest.NewServer()
+	gmux := newTestGmux(server)
+	gmux.tmux.Version = tmux.Version{Major: 3, Minor: 0}
+
+	err := gmux.Start(conf, Options{Detach: true}, Context{})
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+
+	session := expectSession(t, server, "test-session", "web")
+	expectEqual(t, map[string]string{"NODE_ENV": "development"}, session.Env)
+
+	web := session.Window("web")
+	expectEqual(t, map[string]string{"NODE_ENV": "development", "PORT": "3000"}, web.Pane(0).Env)
+	expectEqual(t, map[string]string{"NODE_ENV": "development", "PORT": "3001"}, web.Pane(1).Env)
+
+	server.Client = "test-session"
+	printed, err := gmux.GetConfigFromSession(Options{Project: "test-session"}, Context{})
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+
+	expectEqual(t, map[string]string{"PORT": "3000"}, printed.Windows[0].Env)
+	expectEqual(t, []config.Pane{{Env: map[string]string{"PORT": "3001"}}}, printed.Windows[0].Panes)
+}
+
+func TestStartQueued(t *testing.T) {
+	conf := config.Config{
+		Session: "test-session",
+		Root:    "/root",
+		Windows: []config.Window{
+			{
+				Name:     "win1",
+				Layout:   tmux.MainVertical,
+				Commands: config.Commands("command1", "command2;"),
+				Panes: []config.Pane{
+					{Type: tmux.HSplit, Commands: config.Commands("command3")},
+				},
+			},
+			{Name: "win2"},
+		},
+	}
+
+	server := tmuxtest.NewServer()
+	gmux := newTestGmux(server)
+	gmux.tmux.Queue = &tmux.Queue{}
+
+	err := gmux.Start(conf, Options{Detach: true}, Context{})
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+
+	session := expectSession(t, server, "test-session", "win1", "win2")
+	win1 := session.Window("win1")
+	expectEqual(t, []string{"-l command1", "Enter", "-l command2;", "Enter"}, win1.Pane(0).Keys)
+	expectEqual(t, []string{"-l command3", "Enter"}, win1.Pane(1).Keys)
+	expectEqual(t, tmux.MainVertical, win1.Layout)
+	expectEqual(t, tmux.EvenHorizontal, session.Window("win2").Layout)
+
+	// has-session, new, a command for every new window and pane,
+	// the rest of the window commands and kill-window with move-window.
+	expectEqual(t, 8, len(server.Commands))
+}
+
+func TestStartCommandKeys(t *testing.T) {
+	noEnter := false
+	conf := config.Config{
+		Session: "test-session",
+		Root:    "/root",
+		Windows: []config.Window{{
+			Name: "win1",
+			Commands: []config.Command{
+				{Text: "-la"},
+				{Keys: []string{"C-c", "Up"}},
+				{Text: "Escape", Enter: &noEnter},
+				{Keys: []string{"C-c"}, Literal: true},
+			},
+			Panes: []config.Pane{{
+				Commands: []config.Command{{Text: "make"}, {Text: "make test", Delay: "2s"}},
+			}},
+		}},
+	}
+
+	server := tmuxtest.NewServer()
+	gmux := newTestGmux(server)
+	gmux.tmux.Queue = &tmux.Queue{}
+
+	var delays []time.Duration
+	flushed := 0
+	gmux.sleep = func(d time.Duration) {
+		delays = append(delays, d)
+		flushed = len(server.Commands)
+	}
+
+	err := gmux.Start(conf, Options{Detach: true}, Context{})
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+
+	win1 := expectSession(t, server, "test-session", "win1").Window("win1")
+	expectEqual(t, []string{"-l -la", "Enter", "C-c Up", "-l Escape", "-l C-c"}, win1.Pane(0).Keys)
+	expectEqual(t, []string{"-l make", "Enter", "-l make test", "Enter"}, win1.Pane(1).Keys)
+	expectEqual(t, []time.Duration{2 * time.Second}, delays)
+
+	// The commands before the delay are sent before waiting.
+	if flushed == 0 || !strings.Contains(server.Commands[flushed-1], "send-keys -l -t @1.%2 -- make ; send-keys -t @1.%2 -- Enter") {
+		t.Errorf("expected the commands to be flushed before the delay, got %q", server.Commands[:flushed])
+	}
+}
+
+func TestStartWaitForShell(t *testing.T) {
+	conf := config.Config{
+		Session:      "test-session",
+		Root:         "/root",
+		WaitForShell: true,
+		Windows: []config.Window{{
+			Name:     "win1",
+			Commands: config.Commands("make"),
+			Panes: []config.Pane{
+				{Commands: config.Commands("make test")},
+				{Run: "htop", Commands: config.Commands("t")},
+			},
+		}},
+	}
+
+	server := tmuxtest.NewServer()
+	gmux := newTestGmux(server)
+	gmux.tmux.Queue = &tmux.Queue{}
+
+	// New panes print their prompt after the second wait.
+	server.Cursor = "0,0"
+	waits := 0
+	gmux.sleep = func(time.Duration) {
+		waits++
+		if waits%2 == 0 {
+			for _, w := range server.Session("test-session").Windows {
+				for _, p := range w.Panes {
+					p.Cursor = "2,0"
+				}
+			}
+		}
+	}
+
+	err := gmux.Start(conf, Options{Detach: true}, Context{})
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+
+	win1 := expectSession(t, server, "test-session", "win1").Window("win1")
+	expectEqual(t, []string{"-l make", "Enter"}, win1.Pane(0).Keys)
+	expectEqual(t, []string{"-l make test", "Enter"}, win1.Pane(1).Keys)
+
+	var checks int
+	for _, c := range server.Commands {
+		if strings.Contains(c, "#{cursor_x},#{cursor_y}") {
+			checks++
+		}
+	}
+
+	// Three checks for the window and its pane each, none for htop.
+	expectEqual(t, 6, checks)
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaqaishtyaq/gmux/config"
+	"github.com/aaqaishtyaq/gmux/executor"
+)
+
+// Number of trailing stderr lines of a failed hook command
+// that are kept in the returned error.
+const hookErrorTailLines = 10
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// hookReporter prints hook output line by line and, when the output
+// is a terminal, keeps a spinner with the current status below it.
+type hookReporter struct {
+	out io.Writer
+	tty bool
+
+	mu     sync.Mutex
+	status string
+	frame  int
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newHookReporter(out io.Writer) *hookReporter {
+	return &hookReporter{out: out, tty: isTerminal(out)}
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func (r *hookReporter) Println(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.clearStatus()
+	fmt.Fprintln(r.out, line)
+	r.drawStatus()
+}
+
+// Status replaces the text next to the spinner and starts
+// the spinner if it is not running yet.
+func (r *hookReporter) Status(status string) {
+	if !r.tty {
+		return
+	}
+
+	r.mu.Lock()
+	r.clearStatus()
+	r.status = status
+	r.drawStatus()
+	r.mu.Unlock()
+
+	if r.stop != nil {
+		return
+	}
+
+	r.stop = make(chan struct{})
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				r.mu.Lock()
+				r.clearStatus()
+				r.frame++
+				r.drawStatus()
+				r.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// Done stops the spinner and prints the summary line in its place.
+func (r *hookReporter) Done(summary string) {
+	if r.stop != nil {
+		close(r.stop)
+		r.wg.Wait()
+		r.stop = nil
+	}
+
+	r.mu.Lock()
+	r.clearStatus()
+	r.status = ""
+	r.mu.Unlock()
+
+	r.Println(summary)
+}
+
+func (r *hookReporter) clearStatus() {
+	if r.tty && r.status != "" {
+		fmt.Fprint(r.out, "\r\033[K")
+	}
+}
+
+func (r *hookReporter) drawStatus() {
+	if r.tty && r.status != "" {
+		fmt.Fprintf(r.out, "%s %s", spinnerFrames[r.frame%len(spinnerFrames)], r.status)
+	}
+}
+
+// hookStep is a single shell invocation of a hook.
+type hookStep struct {
+	label      string
+	command    string
+	configPath string
+}
+
+func hookSteps(name string, hook config.Hook) []hookStep {
+	var steps []hookStep
+	for i, c := range hook.Commands {
+		steps = append(steps, hookStep{
+			label:      fmt.Sprintf("#%d", i+1),
+			command:    c,
+			configPath: fmt.Sprintf("%s[%d]", name, i),
+		})
+	}
+
+	if hook.Script != "" {
+		steps = append(steps, hookStep{
+			label:      "script",
+			command:    hook.Script,
+			configPath: name + ".script",
+		})
+	}
+
+	return steps
+}
+
+// shellArgs returns the program and arguments that run a command with the shell.
+// A shell given without arguments gets "-c".
+func shellArgs(shell string) []string {
+	switch shell {
+	case "":
+		return []string{"/bin/sh", "-c"}
+	case config.UserShell:
+		userShell := os.Getenv("SHELL")
+		if userShell == "" {
+			userShell = "/bin/sh"
+		}
+		return []string{userShell, "-c"}
+	}
+
+	args := strings.Fields(shell)
+	if len(args) == 1 {
+		args = append(args, "-c")
+	}
+
+	return args
+}
+
+// runHook runs the hook commands one by one in dir, followed by its script.
+// The hook shell takes precedence over the session shell.
+// Commands inherit the gmux environment extended with env.
+// Unless the hook is quiet, the output of every command is streamed
+// to the gmux output prefixed with the hook name and the command label.
+func (gmux Gmux) runHook(name string, hook config.Hook, sessionShell string, dir string, env map[string]string) error {
+	steps := hookSteps(name, hook)
+	if len(steps) == 0 {
+		return nil
+	}
+
+	shell := hook.Shell
+	if shell == "" {
+		shell = sessionShell
+	}
+
+	var reporter *hookReporter
+	if !hook.Quiet {
+		reporter = newHookReporter(gmux.out())
+	}
+
+	started := time.Now()
+	for i, step := range steps {
+		prefix := fmt.Sprintf("[%s %s] ", name, step.label)
+		if reporter != nil {
+			reporter.Status(fmt.Sprintf("%s (%d/%d): %s", name, i+1, len(steps), firstLine(step.command)))
+		}
+
+		err := gmux.runHookCommand(shellArgs(shell), step.command, dir, env, prefix, reporter)
+		if err != nil {
+			err = withConfigPath(err, step.configPath)
+			if reporter != nil {
+				reporter.Done(fmt.Sprintf("✗ %s failed on %s after %s", name, step.label, time.Since(started).Round(time.Millisecond)))
+			}
+			return err
+		}
+	}
+
+	if reporter != nil {
+		reporter.Done(fmt.Sprintf("✓ %s: %d command(s) finished in %s", name, len(steps), time.Since(started).Round(time.Millisecond)))
+	}
+
+	return nil
+}
+
+func firstLine(s string) string {
+	lines := strings.SplitN(strings.TrimSpace(s), "\n", 2)
+	if len(lines) > 1 {
+		return lines[0] + " …"
+	}
+
+	return lines[0]
+}
+
+func (gmux Gmux) runHookCommand(shell []string, command string, dir string, env map[string]string, prefix string, reporter *hookReporter) error {
+	cmd := exec.Command(shell[0], append(shell[1:], command)...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = os.Environ()
+		for key, value := range env {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+	}
+
+	tail := &executor.Tail{Size: hookErrorTailLines}
+	stderr := &executor.LineWriter{Func: func(line string) {
+		tail.Add(line)
+		if reporter != nil {
+			reporter.Println(prefix + line)
+		}
+	}}
+	cmd.Stderr = stderr
+
+	stdout := &executor.LineWriter{Func: func(line string) {
+		if reporter != nil {
+			reporter.Println(prefix + line)
+		}
+	}}
+	cmd.Stdout = stdout
+
+	if reporter != nil {
+		for _, line := range strings.Split(strings.TrimRight(command, "\n"), "\n") {
+			reporter.Println(prefix + "$ " + line)
+		}
+	}
+
+	err := gmux.executor.ExecQuiet(cmd)
+	stdout.Flush()
+	stderr.Flush()
+
+	var shellErr *executor.ShellError
+	if errors.As(err, &shellErr) {
+		shellErr.Stderr = tail.String()
+	}
+
+	return err
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aaqaishtyaq/gmux/config"
+	"github.com/aaqaishtyaq/gmux/executor"
+	"github.com/aaqaishtyaq/gmux/tmux/tmuxtest"
+)
+
+func TestRunHookStreamsOutput(t *testing.T) {
+	output := &bytes.Buffer{}
+	gmux := Gmux{executor: executor.DefaultExecutor{}, output: output}
+
+	hook := config.Hook{Commands: []string{"echo out", "echo err >&2"}}
+	err := gmux.runHook("before_start", hook, "", ".", nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for _, line := range []string{
+		"[before_start #1] $ echo out",
+		"[before_start #1] out",
+		"[before_start #2] err",
+		"✓ before_start: 2 command(s) finished in",
+	} {
+		if !strings.Contains(output.String(), line) {
+			t.Errorf("expected output to contain %q, got\n%s", line, output.String())
+		}
+	}
+}
+
+func TestRunHookQuiet(t *testing.T) {
+	output := &bytes.Buffer{}
+	gmux := Gmux{executor: executor.DefaultExecutor{}, output: output}
+
+	hook := config.Hook{Commands: []string{"echo out"}, Quiet: true}
+	err := gmux.runHook("stop", hook, "", ".", nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if output.Len() != 0 {
+		t.Errorf("expected no output, got %q", output.String())
+	}
+}
+
+func TestRunHookErrorKeepsStderrTail(t *testing.T) {
+	gmux := Gmux{executor: executor.DefaultExecutor{}}
+
+	hook := config.Hook{Commands: []string{"for i in $(seq 1 20); do echo line$i >&2; done; exit 3"}, Quiet: true}
+	err := gmux.runHook("before_start", hook, "", ".", nil)
+
+	var shellErr *executor.ShellError
+	if !errors.As(err, &shellErr) {
+		t.Fatalf("expected ShellError, got %v", err)
+	}
+
+	lines := strings.Split(shellErr.Stderr, "\n")
+	if len(lines) != hookErrorTailLines || lines[0] != "line11" || lines[len(lines)-1] != "line20" {
+		t.Errorf("unexpected stderr tail %q", shellErr.Stderr)
+	}
+}
+
+func TestRunHookScript(t *testing.T) {
+	output := &bytes.Buffer{}
+	gmux := Gmux{executor: executor.DefaultExecutor{}, output: output}
+
+	hook := config.Hook{Script: "cd /\nexport GREETING=hello\necho $GREETING from $(pwd)\n"}
+	err := gmux.runHook("before_start", hook, "", ".", nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if !strings.Contains(output.String(), "[before_start script] hello from /\n") {
+		t.Errorf("expected script output, got\n%s", output.String())
+	}
+}
+
+func TestRunHookShell(t *testing.T) {
+	server := tmuxtest.NewServer()
+	gmux := Gmux{executor: server}
+
+	err := gmux.runHook("stop", config.Hook{Commands: []string{"one"}}, "bash -lc", ".", nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	err = gmux.runHook("stop", config.Hook{Commands: []string{"two"}, Shell: "zsh"}, "bash -lc", ".", nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	expected := []string{"bash -lc one", "zsh -c two"}
+	if !reflect.DeepEqual(expected, server.External) {
+		t.Errorf("expected %q, got %q", expected, server.External)
+	}
+}
+
+func TestShellArgs(t *testing.T) {
+	t.Setenv("SHELL", "/usr/bin/fish")
+
+	for shell, expected := range map[string][]string{
+		"":               {"/bin/sh", "-c"},
+		config.UserShell: {"/usr/bin/fish", "-c"},
+		"bash":           {"bash", "-c"},
+		"bash -lc":       {"bash", "-lc"},
+	} {
+		if args := shellArgs(shell); !reflect.DeepEqual(expected, args) {
+			t.Errorf("shell %q: expected %q, got %q", shell, expected, args)
+		}
+	}
+}
+
+func TestRunHookEnv(t *testing.T) {
+	output := &bytes.Buffer{}
+	gmux := Gmux{executor: executor.DefaultExecutor{}, output: output}
+
+	hook := config.Hook{Commands: []string{"echo $GMUX_TEST_VAR"}}
+	err := gmux.runHook("before_start", hook, "", ".", map[string]string{"GMUX_TEST_VAR": "from env"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if !strings.Contains(output.String(), "[before_start #1] from env\n") {
+		t.Errorf("expected hook to see the env, got\n%s", output.String())
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aaqaishtyaq/gmux/config"
+
+	"gopkg.in/yaml.v2"
+)
+
+// importers convert the project files of other session managers.
+var importers = map[string]func([]byte) (config.Config, []string, error){
+	"tmuxinator": config.ImportTmuxinator,
+	"tmuxp":      config.ImportTmuxp,
+}
+
+// Import converts the project file source of the format and writes it to dest,
+// or to the config of the session in the store if dest is empty. Existing configs are not
+// overwritten unless force is set. It returns the path of the config and what was not converted,
+// which is also written as comments on top of the config.
+func Import(format string, source string, dest string, store config.Store, force bool) (string, []string, error) {
+	importer, ok := importers[format]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown format %q, expected tmuxinator or tmuxp", format)
+	}
+
+	if source == "" {
+		return "", nil, fmt.Errorf("expected the %s file to import", format)
+	}
+
+	data, err := ioutil.ReadFile(ExpandPath(source))
+	if err != nil {
+		return "", nil, err
+	}
+
+	conf, warnings, err := importer(data)
+	if err != nil {
+		return "", warnings, fmt.Errorf("%s: %w", source, err)
+	}
+
+	if conf.Session == "" {
+		conf.Session = strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	}
+
+	if dest == "" {
+		dest = store.Path(conf.Session)
+	}
+
+	header := []string{fmt.Sprintf("Imported from %s %s", format, source)}
+	for _, warning := range warnings {
+		header = append(header, "Not converted: "+warning)
+	}
+
+	return dest, warnings, writeNewConfig(dest, conf, header, force)
+}
+
+// writeNewConfig writes the config to path with the comments on top,
+// unless a file already exists there and force is false.
+func writeNewConfig(path string, conf config.Config, comments []string, force bool) error {
+	out, err := yaml.Marshal(&conf)
+	if err != nil {
+		return err
+	}
+
+	return writeConfigFile(path, []byte(config.Comments(comments)+string(out)), force)
+}
+
+// writeConfigFile writes data to path, creating its directory,
+// unless a file already exists there and force is false.
+func writeConfigFile(path string, data []byte, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%s already exists, pick another path with --file or overwrite it with --force", path)
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// Export converts the config to the format, tmuxinator by default.
+// What was not converted is written as comments on top and returned.
+func Export(conf config.Config, format string) ([]byte, []string, error) {
+	if format != "" && format != "tmuxinator" {
+		return nil, nil, fmt.Errorf("unknown format %q, expected tmuxinator", format)
+	}
+
+	data, warnings, err := config.ExportTmuxinator(conf)
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	var header []string
+	for _, warning := range warnings {
+		header = append(header, "Not converted: "+warning)
+	}
+
+	return append([]byte(config.Comments(header)), data...), warnings, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aaqaishtyaq/gmux/config"
+)
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "blog.yml")
+	data := "name: blog\nroot: ~/blog\ntmux_options: -f ~/.tmux.mac.conf\nwindows:\n  - editor: vim\n"
+	if err := os.WriteFile(source, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := config.Store{Dir: filepath.Join(dir, "gmux")}
+	path, warnings, err := Import("tmuxinator", source, "", store, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqual(t, store.Path("blog"), path)
+	expectEqual(t, []string{"tmux_options is not supported"}, warnings)
+
+	written, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := "# Imported from tmuxinator " + source + "\n# Not converted: tmux_options is not supported\n"
+	if !strings.HasPrefix(string(written), header) {
+		t.Errorf("expected the warnings on top, got\n%s", written)
+	}
+
+	conf, err := config.GetConfig(path, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqual(t, config.Window{Name: "editor", Commands: config.Commands("vim")}, conf.Windows[0])
+
+	_, _, err = Import("tmuxinator", source, "", store, false)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected the config not to be overwritten, got %v", err)
+	}
+
+	_, _, err = Import("tmuxinator", source, "", store, true)
+	if err != nil {
+		t.Errorf("expected --force to overwrite the config, got %v", err)
+	}
+
+	_, _, err = Import("teamocil", source, "", store, false)
+	if err == nil || err.Error() != `unknown format "teamocil", expected tmuxinator or tmuxp` {
+		t.Errorf("expected an unknown format error, got %v", err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	conf := config.Config{
+		Session: "work",
+		Options: map[string]string{"mouse": "on"},
+		Windows: []config.Window{{Name: "editor", Commands: config.Commands("vim")}},
+	}
+
+	data, warnings, err := Export(conf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectEqual(t, []string{"options is not supported"}, warnings)
+	expectEqual(t, "# Not converted: options is not supported\nname: work\nwindows:\n- editor: vim\n", string(data))
+
+	_, _, err = Export(conf, "tmuxp")
+	if err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,13 @@
+// Package slice has the slice helpers the gmux packages share.
+package slice
+
+// Contains reports whether the slice contains s.
+func Contains(slice []string, s string) bool {
+	for _, e := range slice {
+		if e == s {
+			return true
+		}
+	}
+
+	return false
+}
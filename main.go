@@ -1,11 +1,13 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/aaqaishtyaq/gmux/config"
 	"github.com/aaqaishtyaq/gmux/executor"
@@ -20,7 +22,19 @@ var usage = fmt.Sprintf(`gmux - session manager for tmux. Version %s
 
 Usage:
 	gmux <command> [<project>] [-f, --file <file>] [-w, --windows <window>]... [-a, --attach]
-	[-d, --debug] [--detach] [-i, --inside-current-session] [<key>=<value>]...
+	[-d, --debug] [--detach] [-i, --inside-current-session] [--control] [--json] [<key>=<value>]...
+	gmux send <project> <command> [-w, --windows <window>]... [-p, --panes <pane>]...
+	[--current-command <glob>] [--idle]
+	gmux save <project> [--keep <n>]
+	gmux restore <project> [--snapshot <name>] [--detach]
+	gmux watch [--interval <duration>] [--keep <n>]
+	gmux new <project> [-t, --template <template>] [--force] [<variable>=<value>]...
+	gmux new <project> --from (<Procfile> | <compose file> | compose) [--force]
+	gmux import (tmuxinator | tmuxp) <file> [-f, --file <file>] [--force]
+	gmux export <project> [--to tmuxinator]
+	gmux (cp | mv) <project> <new project> [--rename-session]
+	gmux rm <project> [--stop]
+	gmux path <project>
 
 Options:
 	-f, --file %s
@@ -29,19 +43,49 @@ Options:
 	-i, --inside-current-session %s
 	-d, --debug %s
 	--detach %s
+	--control %s
+	--json %s
+	-p, --panes %s
+	--current-command %s
+	--idle %s
+	--keep %s
+	--snapshot %s
+	--interval %s
+	--to %s
+	--from %s
+	-t, --template %s
+	--force %s
+	--rename-session %s
+	--stop %s
 
 Commands:
-	list    list available project configurations
-	edit    edit project configuration
-	new     new project configuration
-	start   start project session
-	stop    stop project session
-	print   session configuration to stdout
+	list      list available project configurations
+	edit      edit project configuration
+	new       new project configuration
+	start     start project session
+	stop      stop project session
+	print     session configuration to stdout
+	validate  check project configuration
+	doctor    check tmux, configs and the environment
+	send      type a command into the panes of a running session
+	save      save a running session with the scrollback of its panes
+	restore   start a session from its latest snapshot
+	snapshots list the saved snapshots of a session
+	watch     save the project sessions periodically until tmux exits
+	import    convert a tmuxinator or tmuxp project to a gmux config
+	export    convert a gmux config to a tmuxinator project
+	cp        copy a project configuration
+	mv        rename a project configuration
+	rm        remove a project configuration
+	path      print the path of a project configuration
 
 	Examples:
 	$ gmux list
 	$ gmux edit work
 	$ gmux new work
+	$ gmux new shop --template rails root=~/code/shop
+	$ gmux new work --from Procfile
+	$ gmux new work --from compose
 	$ gmux start work
 	$ gmux start work:win1
 	$ gmux start work -w win1
@@ -49,7 +93,23 @@ Commands:
 	$ gmux stop work
 	$ gmux start work --attach
 	$ gmux print > ~/.config/gmux/work.yml
-`, version, FileUsage, WindowsUsage, AttachUsage, InsideCurrentSessionUsage, DebugUsage, DetachUsage)
+	$ gmux validate work
+	$ gmux doctor
+	$ gmux doctor --json
+	$ gmux send work 'git pull' --windows api,web --panes all
+	$ gmux send work 'make test' --panes rails,1 --idle
+	$ gmux save work --keep 5
+	$ gmux snapshots work
+	$ gmux restore work --snapshot 20240102-150405
+	$ gmux watch --interval 5m --keep 20
+	$ gmux import tmuxinator ~/.config/tmuxinator/blog.yml
+	$ gmux import tmuxp ~/.tmuxp/api.json
+	$ gmux export work --to tmuxinator > ~/.config/tmuxinator/work.yml
+	$ gmux cp work work-staging --rename-session
+	$ gmux mv work-staging staging
+	$ gmux rm staging --stop
+	$ gmux path work
+`, version, FileUsage, WindowsUsage, AttachUsage, InsideCurrentSessionUsage, DebugUsage, DetachUsage, ControlUsage, JSONUsage, PanesUsage, CurrentCommandUsage, IdleUsage, KeepUsage, SnapshotUsage, IntervalUsage, ToUsage, FromUsage, TemplateUsage, ForceUsage, RenameSessionUsage, StopUsage)
 
 func main() {
 	options, err := ParseOptions(os.Args[1:], func() {
@@ -71,12 +131,19 @@ func main() {
 	}
 
 	userConfigDir := filepath.Join(ExpandPath("~/"), ".config/gmux")
+	store := config.Store{Dir: userConfigDir}
 
 	var configPath string
 	if options.Config != "" {
 		configPath = options.Config
 	} else {
-		configPath = filepath.Join(userConfigDir, options.Project+".yaml")
+		if options.Project != "" {
+			if err := config.CheckProject(options.Project); err != nil {
+				fmt.Fprint(os.Stderr, errorReport(err))
+				os.Exit(1)
+			}
+		}
+		configPath = store.Path(options.Project)
 	}
 
 	var logger *log.Logger
@@ -89,10 +156,29 @@ func main() {
 	}
 
 	executor := executor.DefaultExecutor{Logger: logger}
-	tmux := tmux.Tmux{Executor: executor}
-	gmux := Gmux{tmux, executor}
+	var control *tmux.ControlClient
+	// watch listens to the notifications of a control mode client.
+	if options.Control || options.Command == CommandWatch {
+		control = &tmux.ControlClient{Fallback: executor, Logger: logger}
+	}
+
+	tmux := tmux.Tmux{Executor: executor, Queue: &tmux.Queue{}}
+	if control != nil {
+		tmux.Executor = control
+	}
+	gmux := Gmux{tmux: tmux, executor: executor, output: os.Stdout}
 	context := CreateContext()
 
+	// Only starting sessions and checking configs needs the tmux version,
+	// the other commands don't run tmux -V for it.
+	detected := false
+	detectVersion := func() {
+		if !detected {
+			gmux.tmux.Version, _ = gmux.tmux.DetectVersion()
+			detected = true
+		}
+	}
+
 	switch options.Command {
 	case CommandStart:
 		if len(options.Windows) == 0 {
@@ -102,15 +188,14 @@ func main() {
 		}
 		conf, err := config.GetConfig(configPath, options.Settings)
 		if err != nil {
-			fmt.Fprint(os.Stderr, err.Error())
+			fmt.Fprint(os.Stderr, errorReport(err))
 			os.Exit(1)
 		}
 
-		err = gmux.Start(conf, options, context)
+		detectVersion()
+		err = gmux.rollBack(conf, options, context, gmux.Start(conf, options, context))
 		if err != nil {
-			fmt.Println("Oops, an error occurred! Rolling back...")
-			fmt.Fprint(os.Stderr, err.Error())
-			_ = gmux.Stop(conf, options, context)
+			fmt.Fprint(os.Stderr, errorReport(err))
 			os.Exit(1)
 		}
 
@@ -122,26 +207,104 @@ func main() {
 		}
 		conf, err := config.GetConfig(configPath, options.Settings)
 		if err != nil {
-			fmt.Fprint(os.Stderr, err.Error())
+			fmt.Fprint(os.Stderr, errorReport(err))
 			os.Exit(1)
 		}
 
 		err = gmux.Stop(conf, options, context)
 		if err != nil {
-			fmt.Fprint(os.Stderr, err.Error())
+			fmt.Fprint(os.Stderr, errorReport(err))
 			os.Exit(1)
 		}
 
 	case CommandNew, CommandEdit:
-		err := config.EditConfig(configPath)
+		if options.Command == CommandNew && options.From != "" {
+			conf, err := NewFromProcesses(options.Project, options.From)
+			if err == nil {
+				err = writeNewConfig(configPath, conf, []string{"Created from " + options.From}, options.Force)
+			}
+
+			if err != nil {
+				fmt.Fprint(os.Stderr, errorReport(err))
+				os.Exit(1)
+			}
+
+			fmt.Printf("✓ %s created from %s\n", configPath, options.From)
+			break
+		}
+
+		stdin := bufio.NewReader(os.Stdin)
+		if options.Command == CommandNew {
+			var ask func(string, string) (string, error)
+			if isTerminal(os.Stdin) {
+				ask = promptVariable(stdin, os.Stdout)
+			}
+
+			data, err := NewFromTemplate(options.Template, store.TemplatesDir(), options.Project, options.Settings, ask)
+			if err == nil {
+				err = writeConfigFile(configPath, []byte(data), options.Force)
+			}
+
+			if err != nil {
+				fmt.Fprint(os.Stderr, errorReport(err))
+				os.Exit(1)
+			}
+		}
+
+		check := func(data string) []error {
+			conf, err := config.ParseConfig(data, options.Settings)
+			if err != nil {
+				return []error{err}
+			}
+
+			detectVersion()
+			return gmux.Validate(conf)
+		}
+
+		saved, err := Edit(configPath, config.EditConfig, check, askInvalid(stdin, os.Stderr))
 		if err != nil {
-			fmt.Fprint(os.Stderr, err.Error())
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+
+		if saved {
+			fmt.Printf("✓ %s saved\n", configPath)
+		}
+	case CommandPath:
+		if _, err := os.Stat(configPath); err != nil {
+			fmt.Fprint(os.Stderr, errorReport(fmt.Errorf("%s: %w", configPath, config.ErrConfigNotFound)))
 			os.Exit(1)
 		}
+
+		fmt.Println(configPath)
+	case CommandCopy, CommandMove:
+		var err error
+		if options.Command == CommandCopy {
+			err = store.Copy(options.Project, options.Destination, options.RenameSession)
+		} else {
+			err = store.Move(options.Project, options.Destination, options.RenameSession)
+		}
+
+		if err != nil {
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ %s\n", store.Path(options.Destination))
+	case CommandRemove:
+		removed, err := gmux.RemoveProject(store, options.Project, options.Stop, askConfirm(bufio.NewReader(os.Stdin), os.Stdout), context)
+		if err != nil {
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+
+		if removed {
+			fmt.Printf("✓ %s removed\n", configPath)
+		}
 	case CommandList:
-		configs, err := config.ListConfigs(userConfigDir)
+		configs, err := store.List()
 		if err != nil {
-			fmt.Fprint(os.Stderr, err.Error())
+			fmt.Fprint(os.Stderr, errorReport(err))
 			os.Exit(1)
 		}
 
@@ -149,16 +312,179 @@ func main() {
 	case CommandPrint:
 		conf, err := gmux.GetConfigFromSession(options, context)
 		if err != nil {
-			fmt.Fprint(os.Stderr, err.Error())
+			fmt.Fprint(os.Stderr, errorReport(err))
 			os.Exit(1)
 		}
 
 		d, err := yaml.Marshal(&conf)
 		if err != nil {
-			fmt.Fprint(os.Stderr, err.Error())
+			fmt.Fprint(os.Stderr, errorReport(err))
 			os.Exit(1)
 		}
 
 		fmt.Println(string(d))
+	case CommandValidate:
+		conf, err := config.GetConfig(configPath, options.Settings)
+		if err != nil {
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+
+		detectVersion()
+		errs := gmux.Validate(conf)
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "✗ %s\n", err)
+		}
+
+		if len(errs) > 0 {
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ %s is valid\n", configPath)
+	case CommandSend:
+		session, err := sessionName(options, configPath)
+		if err != nil {
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+
+		sent, err := gmux.Send(session, options)
+		if err != nil {
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+
+		fmt.Printf("Sent to %d pane(s)\n", sent)
+	case CommandSave:
+		session, err := sessionName(options, configPath)
+		if err != nil {
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+
+		dir, err := gmux.Save(session, snapshotsDir(session), options.Keep, time.Now())
+		if err != nil {
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+
+		fmt.Printf("Saved %s\n", dir)
+	case CommandRestore:
+		session, err := sessionName(options, configPath)
+		if err != nil {
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+
+		snapshot, dir, err := LoadSnapshot(snapshotsDir(session), options.Snapshot)
+		if err != nil {
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+
+		fmt.Printf("Restoring %s...\n", dir)
+		detectVersion()
+		err = gmux.rollBack(snapshot.Config, options, context, gmux.Restore(snapshot, dir, options, context))
+		if err != nil {
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+	case CommandSnapshots:
+		session, err := sessionName(options, configPath)
+		if err != nil {
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+
+		snapshots, err := ListSnapshots(snapshotsDir(session))
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+
+		fmt.Println(strings.Join(snapshots, "\n"))
+	case CommandWatch:
+		socket, err := gmux.tmux.ServerSocket()
+		if err != nil {
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+
+		lock, err := lockWatch(socket)
+		if err != nil {
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+		defer lock.Close()
+
+		fmt.Printf("Watching the sessions on %s...\n", socket)
+		projects := func() []string { return projectSessions(store) }
+		err = gmux.Watch(projects, control.Notifications(), options.Interval, options.Keep)
+		if err != nil {
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+	case CommandImport:
+		path, warnings, err := Import(options.Format, options.Source, options.Config, store, options.Force)
+		printWarnings(warnings)
+		if err != nil {
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ %s imported to %s\n", options.Source, path)
+	case CommandExport:
+		conf, err := config.GetConfig(configPath, options.Settings)
+		if err != nil {
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+
+		data, warnings, err := Export(conf, options.Format)
+		if err != nil {
+			fmt.Fprint(os.Stderr, errorReport(err))
+			os.Exit(1)
+		}
+
+		printWarnings(warnings)
+		fmt.Print(string(data))
+	case CommandDoctor:
+		detectVersion()
+		checks := gmux.Doctor(context, userConfigDir)
+		if options.JSON {
+			err := printChecksJSON(os.Stdout, version, checks)
+			if err != nil {
+				fmt.Fprint(os.Stderr, errorReport(err))
+				os.Exit(1)
+			}
+		} else {
+			printChecks(os.Stdout, checks)
+		}
+
+		if failed(checks) {
+			os.Exit(1)
+		}
+	}
+}
+
+// sessionName returns the session of the config, e.g. of gmux send work
+// or gmux send -f work.yml. A project without a config names its session.
+func sessionName(options Options, configPath string) (string, error) {
+	conf, err := config.GetConfig(configPath, options.Settings)
+	if os.IsNotExist(err) && options.Project != "" && options.Config == "" {
+		return options.Project, nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return conf.Session, nil
+}
+
+// printWarnings prints what import and export could not convert.
+func printWarnings(warnings []string) {
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "! %s\n", warning)
 	}
 }
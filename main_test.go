@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aaqaishtyaq/gmux/config"
+)
+
+func TestSessionName(t *testing.T) {
+	store := config.Store{Dir: t.TempDir()}
+	err := os.WriteFile(store.Path("work"), []byte("session: office\nwindows:\n  - name: code\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name       string
+		options    Options
+		configPath string
+		expected   string
+	}{
+		{"project", Options{Project: "work"}, store.Path("work"), "office"},
+		{"config file", Options{Config: store.Path("work")}, store.Path("work"), "office"},
+		{"project without a config", Options{Project: "scratch"}, store.Path("scratch"), "scratch"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			session, err := sessionName(test.options, test.configPath)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			expectEqual(t, test.expected, session)
+		})
+	}
+
+	_, err = sessionName(Options{Config: filepath.Join(store.Dir, "missing.yml")}, filepath.Join(store.Dir, "missing.yml"))
+	if !os.IsNotExist(err) {
+		t.Errorf("expected a missing config file to fail, got %v", err)
+	}
+}
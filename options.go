@@ -3,20 +3,36 @@ package main
 import (
 	"errors"
 	"strings"
+	"time"
+
+	"github.com/aaqaishtyaq/gmux/internal/slice"
 
 	"github.com/spf13/pflag"
 )
 
 const (
-	CommandStart = "start"
-	CommandStop  = "stop"
-	CommandNew   = "new"
-	CommandEdit  = "edit"
-	CommandList  = "list"
-	CommandPrint = "print"
+	CommandStart     = "start"
+	CommandStop      = "stop"
+	CommandNew       = "new"
+	CommandEdit      = "edit"
+	CommandList      = "list"
+	CommandPrint     = "print"
+	CommandValidate  = "validate"
+	CommandDoctor    = "doctor"
+	CommandSend      = "send"
+	CommandSave      = "save"
+	CommandRestore   = "restore"
+	CommandSnapshots = "snapshots"
+	CommandWatch     = "watch"
+	CommandImport    = "import"
+	CommandExport    = "export"
+	CommandCopy      = "cp"
+	CommandMove      = "mv"
+	CommandRemove    = "rm"
+	CommandPath      = "path"
 )
 
-var validCommands = []string{CommandStart, CommandStop, CommandNew, CommandEdit, CommandList, CommandPrint}
+var validCommands = []string{CommandStart, CommandStop, CommandNew, CommandEdit, CommandList, CommandPrint, CommandValidate, CommandDoctor, CommandSend, CommandSave, CommandRestore, CommandSnapshots, CommandWatch, CommandImport, CommandExport, CommandCopy, CommandMove, CommandRemove, CommandPath}
 
 type Options struct {
 	Command              string
@@ -28,6 +44,30 @@ type Options struct {
 	Detach               bool
 	Debug                bool
 	InsideCurrentSession bool
+	Control              bool
+	JSON                 bool
+	// Keys, Panes, CurrentCommand and Idle are used by send.
+	Keys           string
+	Panes          []string
+	CurrentCommand string
+	Idle           bool
+	// Keep is used by save and watch, Snapshot by restore, Interval by watch.
+	Keep     int
+	Snapshot string
+	Interval time.Duration
+	// Format and Source are used by import, Format by export.
+	Format string
+	Source string
+	// From is the Procfile or compose file new creates the config from,
+	// Template the template it renders otherwise.
+	From     string
+	Template string
+	// Force lets new and import overwrite an existing config.
+	Force bool
+	// Destination and RenameSession are used by cp and mv, Stop by rm.
+	Destination   string
+	RenameSession bool
+	Stop          bool
 }
 
 var ErrHelp = errors.New("help requested")
@@ -39,6 +79,20 @@ const (
 	DebugUsage                = "Print all commands to ~/.config/gmux/gmux.log"
 	FileUsage                 = "A custom path to a config file"
 	InsideCurrentSessionUsage = "Create all windows inside current session"
+	ControlUsage              = "Send commands over a single tmux control mode connection"
+	JSONUsage                 = "Print doctor results as JSON"
+	PanesUsage                = "Panes to send to, by index or title, all by default"
+	CurrentCommandUsage       = "Only send to panes running a command that matches the glob"
+	IdleUsage                 = "Only send to panes idle at a shell prompt"
+	KeepUsage                 = "Number of snapshots to keep, 10 by default"
+	SnapshotUsage             = "Snapshot to restore, the latest by default"
+	IntervalUsage             = "How often watch saves the sessions, 15m by default"
+	ToUsage                   = "Format to export to, tmuxinator by default"
+	FromUsage                 = "Create the config from a Procfile or a docker compose file, compose to find it"
+	TemplateUsage             = "Template to create the config from, built-in or in ~/.config/gmux/templates"
+	ForceUsage                = "Overwrite an existing config"
+	RenameSessionUsage        = "Set the session of the copied or moved config to its new name"
+	StopUsage                 = "Stop the session of the config before removing it"
 )
 
 // Creates a new FlagSet.
@@ -60,7 +114,7 @@ func ParseOptions(argv []string, helpRequested func()) (Options, error) {
 	}
 
 	cmd := argv[0]
-	if !Contains(validCommands, cmd) {
+	if !slice.Contains(validCommands, cmd) {
 		helpRequested()
 		return Options{}, ErrHelp
 	}
@@ -73,6 +127,20 @@ func ParseOptions(argv []string, helpRequested func()) (Options, error) {
 	detach := flags.Bool("detach", false, DetachUsage)
 	debug := flags.BoolP("debug", "d", false, DebugUsage)
 	insideCurrentSession := flags.BoolP("inside-current-session", "i", false, InsideCurrentSessionUsage)
+	control := flags.Bool("control", false, ControlUsage)
+	json := flags.Bool("json", false, JSONUsage)
+	panes := flags.StringSliceP("panes", "p", nil, PanesUsage)
+	currentCommand := flags.String("current-command", "", CurrentCommandUsage)
+	idle := flags.Bool("idle", false, IdleUsage)
+	keep := flags.Int("keep", 0, KeepUsage)
+	snapshot := flags.String("snapshot", "", SnapshotUsage)
+	interval := flags.Duration("interval", 0, IntervalUsage)
+	to := flags.String("to", "", ToUsage)
+	from := flags.String("from", "", FromUsage)
+	template := flags.StringP("template", "t", "", TemplateUsage)
+	force := flags.Bool("force", false, ForceUsage)
+	renameSession := flags.Bool("rename-session", false, RenameSessionUsage)
+	stop := flags.Bool("stop", false, StopUsage)
 
 	err := flags.Parse(argv)
 
@@ -98,6 +166,35 @@ func ParseOptions(argv []string, helpRequested func()) (Options, error) {
 
 	settings := make(map[string]string)
 	userSettings := flags.Args()[1:]
+
+	// import takes the format instead of the project, then the file.
+	format := *to
+	var source string
+	if cmd == CommandImport {
+		positional := flags.Args()[1:]
+		format, project = field(positional, 0), ""
+		source = field(positional, 1)
+		userSettings = nil
+	}
+
+	// cp and mv take the destination after the project.
+	var destination string
+	if cmd == CommandCopy || cmd == CommandMove {
+		destination = field(userSettings, 1)
+		userSettings = nil
+	}
+
+	// send takes the keys after the project.
+	var keys string
+	if cmd == CommandSend {
+		if project != "" && len(userSettings) > 0 {
+			userSettings = userSettings[1:]
+		}
+		if len(userSettings) > 0 {
+			keys = userSettings[0]
+			userSettings = userSettings[1:]
+		}
+	}
 	if len(userSettings) > 0 {
 		for _, kv := range userSettings {
 			s := strings.Split(kv, "=")
@@ -118,5 +215,31 @@ func ParseOptions(argv []string, helpRequested func()) (Options, error) {
 		Detach:               *detach,
 		Debug:                *debug,
 		InsideCurrentSession: *insideCurrentSession,
+		Control:              *control,
+		JSON:                 *json,
+		Keys:                 keys,
+		Panes:                *panes,
+		CurrentCommand:       *currentCommand,
+		Idle:                 *idle,
+		Keep:                 *keep,
+		Snapshot:             *snapshot,
+		Interval:             *interval,
+		Format:               format,
+		Source:               source,
+		From:                 *from,
+		Template:             *template,
+		Force:                *force,
+		Destination:          destination,
+		RenameSession:        *renameSession,
+		Stop:                 *stop,
 	}, nil
 }
+
+// field returns the i-th value, "" if there are fewer.
+func field(values []string, i int) string {
+	if i >= len(values) {
+		return ""
+	}
+
+	return values[i]
+}
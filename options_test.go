@@ -173,6 +173,109 @@ var usageTestTable = []struct {
 		nil,
 		0,
 	},
+	{
+		[]string{"send", "work", "git pull", "--windows", "api,web", "--panes", "all", "--idle"},
+		Options{
+			Command:  "send",
+			Project:  "work",
+			Windows:  []string{"api,web"},
+			Settings: map[string]string{},
+			Keys:     "git pull",
+			Panes:    []string{"all"},
+			Idle:     true,
+		},
+		nil,
+		0,
+	},
+	{
+		[]string{"send", "-f", "test.yml", "make test", "-p", "rails,1", "--current-command", "zsh", "a=b"},
+		Options{
+			Command:        "send",
+			Config:         "test.yml",
+			Windows:        []string{},
+			Settings:       map[string]string{"a": "b"},
+			Keys:           "make test",
+			Panes:          []string{"rails", "1"},
+			CurrentCommand: "zsh",
+		},
+		nil,
+		0,
+	},
+	{
+		[]string{"import", "tmuxinator", "~/.tmuxinator/blog.yml", "-f", "blog.yml"},
+		Options{
+			Command:  "import",
+			Config:   "blog.yml",
+			Windows:  []string{},
+			Settings: map[string]string{},
+			Format:   "tmuxinator",
+			Source:   "~/.tmuxinator/blog.yml",
+		},
+		nil,
+		0,
+	},
+	{
+		[]string{"export", "work", "--to", "tmuxinator"},
+		Options{
+			Command:  "export",
+			Project:  "work",
+			Windows:  []string{},
+			Settings: map[string]string{},
+			Format:   "tmuxinator",
+		},
+		nil,
+		0,
+	},
+	{
+		[]string{"new", "work", "--from", "Procfile"},
+		Options{
+			Command:  "new",
+			Project:  "work",
+			Windows:  []string{},
+			Settings: map[string]string{},
+			From:     "Procfile",
+		},
+		nil,
+		0,
+	},
+	{
+		[]string{"new", "shop", "--template", "rails", "--force", "root=~/code/shop"},
+		Options{
+			Command:  "new",
+			Project:  "shop",
+			Windows:  []string{},
+			Settings: map[string]string{"root": "~/code/shop"},
+			Template: "rails",
+			Force:    true,
+		},
+		nil,
+		0,
+	},
+	{
+		[]string{"cp", "work", "work-staging", "--rename-session"},
+		Options{
+			Command:       "cp",
+			Project:       "work",
+			Windows:       []string{},
+			Settings:      map[string]string{},
+			Destination:   "work-staging",
+			RenameSession: true,
+		},
+		nil,
+		0,
+	},
+	{
+		[]string{"rm", "work", "--stop"},
+		Options{
+			Command:  "rm",
+			Project:  "work",
+			Windows:  []string{},
+			Settings: map[string]string{},
+			Stop:     true,
+		},
+		nil,
+		0,
+	},
 	{
 		[]string{"start", "--help"},
 		Options{},
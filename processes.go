@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aaqaishtyaq/gmux/config"
+)
+
+// FromCompose makes NewFromProcesses look for a compose file in the current directory.
+const FromCompose = "compose"
+
+// NewFromProcesses returns the config of a session running the processes
+// declared in from: a Procfile, a docker compose file, or FromCompose.
+// Every process gets a pane, the root is the directory of the file, which
+// names the session if it is empty, and the .env file next to it, if any,
+// is loaded with env_file.
+func NewFromProcesses(session string, from string) (config.Config, error) {
+	if from == FromCompose {
+		from = ""
+		for _, name := range config.ComposeFiles {
+			if _, err := os.Stat(name); err == nil {
+				from = name
+				break
+			}
+		}
+
+		if from == "" {
+			return config.Config{}, fmt.Errorf("no compose file in the current directory, expected one of %s", strings.Join(config.ComposeFiles, ", "))
+		}
+	}
+
+	path, err := filepath.Abs(ExpandPath(from))
+	if err != nil {
+		return config.Config{}, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config.Config{}, err
+	}
+
+	var processes []config.Process
+	var window string
+	switch name := filepath.Base(path); {
+	case strings.HasPrefix(name, "Procfile"):
+		window = "procs"
+		processes, err = config.ParseProcfile(string(data))
+	case filepath.Ext(name) == ".yml" || filepath.Ext(name) == ".yaml":
+		window = "services"
+		processes, err = config.ParseCompose(data, name)
+	default:
+		return config.Config{}, fmt.Errorf("%s: expected a Procfile or a docker compose file", from)
+	}
+
+	if err != nil {
+		return config.Config{}, fmt.Errorf("%s: %w", from, err)
+	}
+
+	if len(processes) == 0 {
+		return config.Config{}, fmt.Errorf("%s: no processes to run", from)
+	}
+
+	root := filepath.Dir(path)
+	if session == "" {
+		session = filepath.Base(root)
+	}
+
+	conf := config.ProcessesConfig(session, root, window, processes, defaultRebalancePanesThreshold)
+	if _, err := os.Stat(filepath.Join(root, ".env")); err == nil {
+		conf.EnvFile = ".env"
+	}
+
+	return conf, nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewFromProcesses(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Procfile.dev": "web: bin/rails server\nworker: bundle exec sidekiq\n",
+		".env":         "PORT=3000\n",
+		"build.sh":     "make\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conf, err := NewFromProcesses("", filepath.Join(dir, "Procfile.dev"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectEqual(t, filepath.Base(dir), conf.Session)
+	expectEqual(t, dir, conf.Root)
+	expectEqual(t, ".env", conf.EnvFile)
+	expectEqual(t, 1, len(conf.Windows))
+	expectEqual(t, "procs", conf.Windows[0].Name)
+	expectEqual(t, "web", conf.Windows[0].Title)
+	expectEqual(t, "worker", conf.Windows[0].Panes[0].Title)
+
+	_, err = NewFromProcesses("work", filepath.Join(dir, "build.sh"))
+	if err == nil {
+		t.Error("expected an error for a file that is not a Procfile or a compose file")
+	}
+}
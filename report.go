@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aaqaishtyaq/gmux/executor"
+)
+
+// errorReport renders an error for the user. Shell errors are expanded
+// into a report with the failed command, its location in the config,
+// working directory, exit code and stderr.
+func errorReport(err error) string {
+	var shellErr *executor.ShellError
+	if !errors.As(err, &shellErr) {
+		return err.Error() + "\n"
+	}
+
+	var b strings.Builder
+	fmt.Fprintln(&b, "Command failed")
+	if shellErr.ConfigPath != "" {
+		fmt.Fprintf(&b, "  config:    %s\n", shellErr.ConfigPath)
+	}
+	fmt.Fprintf(&b, "  command:   %s\n", shellErr.Command)
+	if shellErr.Dir != "" {
+		fmt.Fprintf(&b, "  directory: %s\n", shellErr.Dir)
+	}
+	if shellErr.ExitCode >= 0 {
+		fmt.Fprintf(&b, "  exit code: %d\n", shellErr.ExitCode)
+	} else {
+		fmt.Fprintf(&b, "  error:     %v\n", shellErr.Err)
+	}
+	if shellErr.Stderr != "" {
+		fmt.Fprintln(&b, "  stderr:")
+		for _, line := range strings.Split(shellErr.Stderr, "\n") {
+			fmt.Fprintf(&b, "    %s\n", line)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aaqaishtyaq/gmux/executor"
+)
+
+func TestErrorReport(t *testing.T) {
+	err := &executor.ShellError{
+		Command:    "tmux neww -Pd -t test: -n win",
+		Err:        errors.New("exit status 1"),
+		ExitCode:   1,
+		Stderr:     "can't find session: test\nsecond line",
+		Dir:        "/tmp",
+		ConfigPath: "windows[2]",
+	}
+
+	expected := `Command failed
+  config:    windows[2]
+  command:   tmux neww -Pd -t test: -n win
+  directory: /tmp
+  exit code: 1
+  stderr:
+    can't find session: test
+    second line
+`
+
+	if report := errorReport(err); report != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, report)
+	}
+}
+
+func TestErrorReportPlainError(t *testing.T) {
+	if report := errorReport(errors.New("oops")); report != "oops\n" {
+		t.Errorf("expected %q, got %q", "oops\n", report)
+	}
+}
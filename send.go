@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aaqaishtyaq/gmux/internal/slice"
+)
+
+// shells are the commands of the panes idle at a prompt, see gmux send --idle.
+var shells = []string{"sh", "bash", "zsh", "fish", "dash", "ksh", "mksh", "tcsh", "csh", "nu", "xonsh"}
+
+// Send sends options.Keys to the panes of a running session that match
+// the windows, the pane selectors and the filters in the options.
+// It returns the number of panes the keys were sent to.
+func (gmux Gmux) Send(session string, options Options) (int, error) {
+	if options.Keys == "" {
+		return 0, errors.New("nothing to send, expected the keys after the session")
+	}
+
+	if options.CurrentCommand != "" {
+		if _, err := path.Match(options.CurrentCommand, ""); err != nil {
+			return 0, fmt.Errorf("invalid --current-command %q: %w", options.CurrentCommand, err)
+		}
+	}
+
+	if !gmux.tmux.SessionExists(session + ":") {
+		return 0, fmt.Errorf("session %q is not running", session)
+	}
+
+	tmuxWindows, err := gmux.tmux.ListWindows(session)
+	if err != nil {
+		return 0, err
+	}
+
+	windows := splitList(options.Windows)
+	for _, name := range windows {
+		found := false
+		for _, w := range tmuxWindows {
+			found = found || w.Name == name
+		}
+
+		if !found {
+			return 0, fmt.Errorf("session %q has no window %q", session, name)
+		}
+	}
+
+	selectors := splitList(options.Panes)
+	sent := 0
+	for _, w := range tmuxWindows {
+		if len(windows) > 0 && !slice.Contains(windows, w.Name) {
+			continue
+		}
+
+		panes, err := gmux.tmux.ListPanes(session + ":" + w.Id)
+		if err != nil {
+			return sent, err
+		}
+
+		for _, p := range panes {
+			if !selectsPane(selectors, p.Index, p.Title) {
+				continue
+			}
+
+			if options.CurrentCommand != "" {
+				if ok, _ := path.Match(options.CurrentCommand, p.Command); !ok {
+					continue
+				}
+			}
+
+			if options.Idle && !isShell(p.Command) {
+				continue
+			}
+
+			err = gmux.tmux.SendCommand(p.Id, options.Keys)
+			if err != nil {
+				return sent, err
+			}
+			sent++
+		}
+	}
+
+	if sent == 0 {
+		return 0, fmt.Errorf("no panes in session %q match", session)
+	}
+
+	return sent, gmux.tmux.Flush()
+}
+
+// selectsPane reports whether one of the selectors is the pane index or title.
+// No selectors and "all" select every pane.
+func selectsPane(selectors []string, index string, title string) bool {
+	if len(selectors) == 0 || slice.Contains(selectors, "all") {
+		return true
+	}
+
+	return slice.Contains(selectors, index) || (title != "" && slice.Contains(selectors, title))
+}
+
+// isShell reports whether the pane command is a shell,
+// one of the known ones or the user's $SHELL.
+func isShell(command string) bool {
+	command = strings.TrimPrefix(command, "-")
+	if shell := os.Getenv("SHELL"); shell != "" && filepath.Base(shell) == command {
+		return true
+	}
+
+	return slice.Contains(shells, command)
+}
+
+// splitList splits comma separated values, e.g. --windows api,web.
+func splitList(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+
+	return list
+}
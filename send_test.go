@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aaqaishtyaq/gmux/config"
+	"github.com/aaqaishtyaq/gmux/tmux/tmuxtest"
+)
+
+func TestSend(t *testing.T) {
+	t.Setenv("SHELL", "/usr/bin/fish")
+
+	for _, test := range []struct {
+		name     string
+		options  Options
+		expected map[string][]string
+		err      string
+	}{
+		{
+			name:    "all panes",
+			options: Options{Keys: "git pull"},
+			expected: map[string][]string{
+				"api.0": {"-l git pull", "Enter"}, "api.1": {"-l git pull", "Enter"},
+				"web.0": {"-l git pull", "Enter"}, "web.1": {"-l git pull", "Enter"}, "web.2": {"-l git pull", "Enter"},
+				"db.0": {"-l git pull", "Enter"},
+			},
+		},
+		{
+			name:    "windows and pane indexes",
+			options: Options{Keys: "ls", Windows: []string{"api,web"}, Panes: []string{"all"}},
+			expected: map[string][]string{
+				"api.0": {"-l ls", "Enter"}, "api.1": {"-l ls", "Enter"},
+				"web.0": {"-l ls", "Enter"}, "web.1": {"-l ls", "Enter"}, "web.2": {"-l ls", "Enter"},
+			},
+		},
+		{
+			name:     "pane titles and indexes",
+			options:  Options{Keys: "ls", Panes: []string{"rails", "2"}},
+			expected: map[string][]string{"web.1": {"-l ls", "Enter"}, "web.2": {"-l ls", "Enter"}},
+		},
+		{
+			name:     "current command",
+			options:  Options{Keys: "rs", CurrentCommand: "node*"},
+			expected: map[string][]string{"api.1": {"-l rs", "Enter"}},
+		},
+		{
+			name:    "idle",
+			options: Options{Keys: "make", Idle: true},
+			expected: map[string][]string{
+				"api.0": {"-l make", "Enter"}, "web.0": {"-l make", "Enter"}, "web.2": {"-l make", "Enter"},
+			},
+		},
+		{
+			name:    "missing window",
+			options: Options{Keys: "ls", Windows: []string{"api,worker"}},
+			err:     `session "work" has no window "worker"`,
+		},
+		{
+			name:    "no matching panes",
+			options: Options{Keys: "ls", Panes: []string{"sidekiq"}},
+			err:     `no panes in session "work" match`,
+		},
+		{
+			name:    "no keys",
+			options: Options{},
+			err:     "nothing to send, expected the keys after the session",
+		},
+	} {
+		server := tmuxtest.NewServer()
+		gmux := newTestGmux(server)
+
+		err := gmux.Start(config.Config{
+			Session: "work",
+			Root:    "/code",
+			Windows: []config.Window{
+				{Name: "api", Panes: []config.Pane{{}}},
+				{Name: "web", Panes: []config.Pane{{Title: "rails"}, {}}},
+				{Name: "db"},
+			},
+		}, Options{Detach: true}, Context{})
+		if err != nil {
+			t.Fatalf("%s: start: %v", test.name, err)
+		}
+
+		session := server.Session("work")
+		session.Window("api").Pane(1).Command = "node"
+		session.Window("web").Pane(1).Command = "ruby"
+		session.Window("db").Pane(0).Command = "psql"
+		session.Window("web").Pane(2).Command = "-fish"
+
+		_, err = gmux.Send("work", test.options)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: expected error %q, got %v", test.name, test.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		keys := map[string][]string{}
+		for _, w := range session.Windows {
+			for i, p := range w.Panes {
+				if len(p.Keys) > 0 {
+					keys[fmt.Sprintf("%s.%d", w.Name, i)] = p.Keys
+				}
+			}
+		}
+		expectEqual(t, test.expected, keys)
+	}
+}
+
+func TestSendStoppedSession(t *testing.T) {
+	gmux := newTestGmux(tmuxtest.NewServer())
+
+	_, err := gmux.Send("work", Options{Keys: "ls"})
+	if err == nil || err.Error() != `session "work" is not running` {
+		t.Errorf("expected an error for a stopped session, got %v", err)
+	}
+}
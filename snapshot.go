@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aaqaishtyaq/gmux/config"
+	"github.com/aaqaishtyaq/gmux/internal/slice"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Snapshots are saved as <session>/<snapshotTime>/snapshot.yaml
+// in the snapshots directory, next to the scrollback of every pane.
+const (
+	snapshotTime         = "20060102-150405"
+	snapshotFile         = "snapshot.yaml"
+	defaultSnapshotsKept = 10
+)
+
+// ErrSessionRunning is returned by Restore, a running session isn't replaced.
+var ErrSessionRunning = errors.New("is running, stop it before restoring it")
+
+// restoreCommands are started again by restore. Other commands,
+// e.g. servers, need their arguments, which tmux doesn't report.
+var restoreCommands = []string{"vi", "vim", "nvim", "emacs", "htop", "top"}
+
+// Snapshot is a saved session: the config it can be started with
+// and the state of its panes.
+type Snapshot struct {
+	Session string         `yaml:"session"`
+	Created time.Time      `yaml:"created"`
+	Config  config.Config  `yaml:"config"`
+	Panes   []PaneSnapshot `yaml:"panes"`
+}
+
+// PaneSnapshot is the state of a pane of Snapshot.Config.
+type PaneSnapshot struct {
+	// Window is the index of the window in the config.
+	Window int `yaml:"window"`
+	// Pane is 0 for the window pane, i for the i-th pane of the window.
+	Pane    int    `yaml:"pane"`
+	Root    string `yaml:"root"`
+	Command string `yaml:"command"`
+	// Scrollback is the file with the pane history, in the snapshot directory.
+	Scrollback string `yaml:"scrollback"`
+}
+
+// stateDir returns the gmux directory in $XDG_STATE_HOME or ~/.local/state.
+func stateDir() string {
+	state := os.Getenv("XDG_STATE_HOME")
+	if state == "" {
+		state = filepath.Join(ExpandPath("~/"), ".local/state")
+	}
+
+	return filepath.Join(state, "gmux")
+}
+
+// snapshotsDir returns the directory of the session snapshots.
+func snapshotsDir(session string) string {
+	return filepath.Join(stateDir(), "snapshots", session)
+}
+
+// Save captures the running session with the scrollback of every pane
+// into a new snapshot in dir, and removes all but the keep newest ones,
+// defaultSnapshotsKept if keep is 0. It returns the directory of the snapshot.
+func (gmux Gmux) Save(session string, dir string, keep int, now time.Time) (string, error) {
+	if keep == 0 {
+		keep = defaultSnapshotsKept
+	}
+
+	if !gmux.tmux.SessionExists(session + ":") {
+		return "", fmt.Errorf("session %q is not running", session)
+	}
+
+	conf, err := gmux.sessionConfig(session)
+	if err != nil {
+		return "", err
+	}
+	conf.Session = session
+
+	snapshotDir := filepath.Join(dir, now.Format(snapshotTime))
+	err = os.MkdirAll(snapshotDir, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	snapshot := Snapshot{Session: session, Created: now, Config: conf}
+
+	tmuxWindows, err := gmux.tmux.ListWindows(session)
+	if err != nil {
+		return "", err
+	}
+
+	// Windows and panes are listed in the order of the config.
+	for wIndex, w := range tmuxWindows {
+		tmuxPanes, err := gmux.tmux.ListPanes(session + ":" + w.Id)
+		if err != nil {
+			return "", err
+		}
+
+		for pIndex, p := range tmuxPanes {
+			scrollback, err := gmux.tmux.CapturePane(p.Id)
+			if err != nil {
+				return "", err
+			}
+
+			if scrollback != "" {
+				scrollback += "\n"
+			}
+
+			name := fmt.Sprintf("%d.%d.txt", wIndex, pIndex)
+			err = ioutil.WriteFile(filepath.Join(snapshotDir, name), []byte(scrollback), 0644)
+			if err != nil {
+				return "", err
+			}
+
+			snapshot.Panes = append(snapshot.Panes, PaneSnapshot{
+				Window:     wIndex,
+				Pane:       pIndex,
+				Root:       p.Root,
+				Command:    p.Command,
+				Scrollback: name,
+			})
+		}
+	}
+
+	data, err := yaml.Marshal(&snapshot)
+	if err != nil {
+		return "", err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(snapshotDir, snapshotFile), data, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return snapshotDir, pruneSnapshots(dir, keep)
+}
+
+// ListSnapshots returns the snapshot names in dir, oldest first.
+func ListSnapshots(dir string) ([]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []string
+	for _, file := range files {
+		if _, err := time.Parse(snapshotTime, file.Name()); err == nil && file.IsDir() {
+			snapshots = append(snapshots, file.Name())
+		}
+	}
+	sort.Strings(snapshots)
+
+	return snapshots, nil
+}
+
+// pruneSnapshots removes all but the keep newest snapshots in dir.
+func pruneSnapshots(dir string, keep int) error {
+	snapshots, err := ListSnapshots(dir)
+	if err != nil || keep <= 0 || len(snapshots) <= keep {
+		return err
+	}
+
+	for _, name := range snapshots[:len(snapshots)-keep] {
+		err := os.RemoveAll(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadSnapshot reads the snapshot with the name from dir, the newest one if name is empty.
+func LoadSnapshot(dir string, name string) (Snapshot, string, error) {
+	if name == "" {
+		snapshots, err := ListSnapshots(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return Snapshot{}, "", err
+		}
+
+		if len(snapshots) == 0 {
+			return Snapshot{}, "", errors.New("no snapshots in " + dir)
+		}
+		name = snapshots[len(snapshots)-1]
+	}
+
+	snapshotDir := filepath.Join(dir, name)
+	data, err := ioutil.ReadFile(filepath.Join(snapshotDir, snapshotFile))
+	if err != nil {
+		return Snapshot{}, "", err
+	}
+
+	snapshot := Snapshot{}
+	err = yaml.Unmarshal(data, &snapshot)
+	if err != nil {
+		return Snapshot{}, "", fmt.Errorf("%s: %w", snapshotDir, err)
+	}
+
+	return snapshot, snapshotDir, nil
+}
+
+// Restore starts the session of the snapshot in snapshotDir.
+// Every pane prints its scrollback before its shell starts,
+// and the commands in restoreCommands are started again.
+func (gmux Gmux) Restore(snapshot Snapshot, snapshotDir string, options Options, context Context) error {
+	if gmux.tmux.SessionExists(snapshot.Session + ":") {
+		return fmt.Errorf("session %q %w", snapshot.Session, ErrSessionRunning)
+	}
+
+	conf := snapshot.Config
+	conf.Windows = make([]config.Window, len(snapshot.Config.Windows))
+	for i, w := range snapshot.Config.Windows {
+		w.Panes = append([]config.Pane{}, w.Panes...)
+		conf.Windows[i] = w
+	}
+
+	for _, p := range snapshot.Panes {
+		if p.Window < 0 || p.Window >= len(conf.Windows) || p.Pane < 0 || p.Pane > len(conf.Windows[p.Window].Panes) {
+			return nothingStarted(fmt.Errorf("%s: pane %d.%d is not in the config", snapshotDir, p.Window, p.Pane))
+		}
+
+		run := replayCommand(filepath.Join(snapshotDir, p.Scrollback))
+		var commands []config.Command
+		if slice.Contains(restoreCommands, p.Command) {
+			commands = config.Commands(p.Command)
+		}
+
+		w := &conf.Windows[p.Window]
+		if p.Pane == 0 {
+			w.Run = run
+			w.Commands = commands
+			continue
+		}
+
+		w.Panes[p.Pane-1].Run = run
+		w.Panes[p.Pane-1].Commands = commands
+	}
+
+	return gmux.Start(conf, options, context)
+}
+
+// replayCommand prints the scrollback file and replaces itself with the user's shell.
+func replayCommand(path string) string {
+	return "cat " + shellQuote(path) + `; exec "${SHELL:-/bin/sh}"`
+}
+
+// shellQuote quotes s for sh, single quoted strings are taken literally.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aaqaishtyaq/gmux/config"
+	"github.com/aaqaishtyaq/gmux/tmux/tmuxtest"
+)
+
+func TestSaveRestore(t *testing.T) {
+	server := tmuxtest.NewServer()
+	gmux := newTestGmux(server)
+	dir := t.TempDir()
+
+	conf := config.Config{
+		Session: "work",
+		Root:    "/code",
+		Windows: []config.Window{
+			{Name: "editor", Panes: []config.Pane{{Root: "docs", Title: "docs"}}},
+			{Name: "logs"},
+		},
+	}
+	err := gmux.Start(conf, Options{Detach: true}, Context{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := server.Session("work")
+	session.Window("editor").Pane(0).Command = "nvim"
+	session.Window("editor").Pane(0).History = "~ main.go\n\n"
+	session.Window("logs").Pane(0).History = "$ tail -f log/dev.log"
+
+	created := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		_, err = gmux.Save("work", dir, 2, created.Add(time.Duration(i)*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshots, err := ListSnapshots(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqual(t, []string{"20240102-150505", "20240102-150605"}, snapshots)
+
+	snapshot, snapshotDir, err := LoadSnapshot(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqual(t, filepath.Join(dir, "20240102-150605"), snapshotDir)
+	expectEqual(t, "work", snapshot.Session)
+	expectEqual(t, []PaneSnapshot{
+		{Window: 0, Pane: 0, Root: "/code", Command: "nvim", Scrollback: "0.0.txt"},
+		{Window: 0, Pane: 1, Root: "/code/docs", Command: "zsh", Scrollback: "0.1.txt"},
+		{Window: 1, Pane: 0, Root: "/code", Command: "zsh", Scrollback: "1.0.txt"},
+	}, snapshot.Panes)
+
+	scrollback, err := ioutil.ReadFile(filepath.Join(snapshotDir, "0.0.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqual(t, "~ main.go\n", string(scrollback))
+
+	err = gmux.Restore(snapshot, snapshotDir, Options{Detach: true}, Context{})
+	if !errors.Is(err, ErrSessionRunning) {
+		t.Fatalf("expected ErrSessionRunning, got %v", err)
+	}
+
+	_, err = gmux.tmux.StopSession("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = gmux.Restore(snapshot, snapshotDir, Options{Detach: true}, Context{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session = server.Session("work")
+	editor := session.Window("editor")
+	expectEqual(t, replayCommand(filepath.Join(snapshotDir, "0.0.txt")), editor.Pane(0).Run)
+	expectEqual(t, []string{"-l nvim", "Enter"}, editor.Pane(0).Keys)
+	expectEqual(t, replayCommand(filepath.Join(snapshotDir, "0.1.txt")), editor.Pane(1).Run)
+	expectEqual(t, "/code/docs", editor.Pane(1).Root)
+	expectEqual(t, "docs", editor.Pane(1).Title)
+	expectEqual(t, 0, len(session.Window("logs").Pane(0).Keys))
+}
+
+func TestLoadSnapshotMissing(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "work")
+
+	_, _, err := LoadSnapshot(dir, "")
+	if err == nil || err.Error() != "no snapshots in "+dir {
+		t.Errorf("expected an error without snapshots, got %v", err)
+	}
+}
+
+func TestReplayCommand(t *testing.T) {
+	expectEqual(t, `cat '/tmp/it'\''s/0.0.txt'; exec "${SHELL:-/bin/sh}"`, replayCommand("/tmp/it's/0.0.txt"))
+}
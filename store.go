@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aaqaishtyaq/gmux/config"
+)
+
+// RemoveProject removes the config of the project once confirm agrees.
+// It refuses to while the session of the config is running, unless stop
+// is set, which stops the session first. It reports whether it was removed.
+func (gmux Gmux) RemoveProject(store config.Store, project string, stop bool, confirm func(string) (bool, error), context Context) (bool, error) {
+	err := config.CheckProject(project)
+	if err != nil {
+		return false, err
+	}
+
+	path := store.Path(project)
+	if !store.Exists(project) {
+		return false, fmt.Errorf("%s: %w", path, config.ErrConfigNotFound)
+	}
+
+	// A broken config can still be removed, its session is the project then.
+	conf, err := config.GetConfig(path, map[string]string{})
+	if err != nil || conf.Session == "" {
+		conf = config.Config{Session: project}
+	}
+
+	running := gmux.tmux.SessionExists(conf.Session)
+	if running && !stop {
+		return false, fmt.Errorf("session %q is running, stop it first or pass --stop", conf.Session)
+	}
+
+	ok, err := confirm(path)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if running {
+		err = gmux.Stop(conf, Options{}, context)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, store.Remove(project)
+}
+
+// askConfirm asks on out whether to remove the config at a path and
+// reads the answer from in, no by default.
+func askConfirm(in *bufio.Reader, out io.Writer) func(string) (bool, error) {
+	return func(path string) (bool, error) {
+		fmt.Fprintf(out, "Remove %s? [y/N] ", path)
+
+		answer, err := in.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "y" || answer == "yes", nil
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aaqaishtyaq/gmux/config"
+	"github.com/aaqaishtyaq/gmux/tmux/tmuxtest"
+)
+
+func TestRemoveProject(t *testing.T) {
+	store := config.Store{Dir: t.TempDir()}
+	if err := os.WriteFile(store.Path("work"), []byte("session: api\nroot: /tmp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := tmuxtest.NewServer()
+	server.AddSession("api", "/tmp")
+	gmux := newTestGmux(server)
+
+	var asked []string
+	confirm := func(answer bool) func(string) (bool, error) {
+		return func(path string) (bool, error) {
+			asked = append(asked, path)
+			return answer, nil
+		}
+	}
+
+	_, err := gmux.RemoveProject(store, "../work", true, confirm(true), Context{})
+	if err == nil || err.Error() != `invalid project name "../work"` {
+		t.Errorf("expected an invalid project name error, got %v", err)
+	}
+
+	_, err = gmux.RemoveProject(store, "work", false, confirm(true), Context{})
+	if err == nil || err.Error() != `session "api" is running, stop it first or pass --stop` {
+		t.Errorf("expected a running session error, got %v", err)
+	}
+	expectEqual(t, 0, len(asked))
+
+	removed, err := gmux.RemoveProject(store, "work", true, confirm(false), Context{})
+	if err != nil || removed {
+		t.Errorf("expected the config to be kept, got %v, %v", removed, err)
+	}
+	expectEqual(t, true, store.Exists("work"))
+	expectEqual(t, true, server.Session("api") != nil)
+
+	removed, err = gmux.RemoveProject(store, "work", true, confirm(true), Context{})
+	if err != nil || !removed {
+		t.Fatalf("expected the config to be removed, got %v, %v", removed, err)
+	}
+	expectEqual(t, false, store.Exists("work"))
+	expectEqual(t, true, server.Session("api") == nil)
+	expectEqual(t, []string{store.Path("work"), store.Path("work")}, asked)
+}
+
+func TestAskConfirm(t *testing.T) {
+	for input, expected := range map[string]bool{"y\n": true, "YES\n": true, "\n": false, "": false} {
+		out := &bytes.Buffer{}
+		ok, err := askConfirm(bufio.NewReader(strings.NewReader(input)), out)("work.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectEqual(t, expected, ok)
+		expectEqual(t, "Remove work.yaml? [y/N] ", out.String())
+	}
+}
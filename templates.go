@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aaqaishtyaq/gmux/config"
+)
+
+// NewFromTemplate renders the template called name, from dir or the
+// built-in ones. Variables not set in settings are asked with ask, nil
+// keeps their defaults: the project for session, the current directory
+// for root and empty for the others.
+func NewFromTemplate(name string, dir string, project string, settings map[string]string, ask func(string, string) (string, error)) (string, error) {
+	if name == "" {
+		name = config.DefaultTemplate
+	}
+
+	text, err := config.LoadTemplate(dir, name)
+	if err != nil {
+		return "", err
+	}
+
+	names, err := config.TemplateVariables(text)
+	if err != nil {
+		return "", fmt.Errorf("template %s: %w", name, err)
+	}
+
+	defaults := map[string]string{"session": project, "root": currentDir()}
+	variables := map[string]string{}
+	for _, variable := range names {
+		value, ok := settings[variable]
+		if !ok {
+			value = defaults[variable]
+			if ask != nil {
+				value, err = ask(variable, value)
+				if err != nil {
+					return "", err
+				}
+			}
+		}
+
+		variables[variable] = value
+	}
+
+	return config.RenderTemplate(text, variables)
+}
+
+// promptVariable asks for the value of a template variable on out and
+// reads it from in, an empty answer keeps the default.
+func promptVariable(in *bufio.Reader, out io.Writer) func(string, string) (string, error) {
+	return func(variable string, value string) (string, error) {
+		if value != "" {
+			fmt.Fprintf(out, "%s [%s]: ", variable, value)
+		} else {
+			fmt.Fprintf(out, "%s: ", variable)
+		}
+
+		answer, err := in.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+
+		if answer = strings.TrimSpace(answer); answer != "" {
+			return answer, nil
+		}
+
+		return value, nil
+	}
+}
+
+// currentDir returns the working directory, with ~ for the home directory.
+func currentDir() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "~/"
+	}
+
+	home, err := os.UserHomeDir()
+	if err == nil && (dir == home || strings.HasPrefix(dir, home+string(filepath.Separator))) {
+		return "~" + strings.TrimPrefix(dir, home)
+	}
+
+	return dir
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNewFromTemplate(t *testing.T) {
+	out := &bytes.Buffer{}
+	ask := promptVariable(bufio.NewReader(strings.NewReader("shop-dev\n")), out)
+
+	data, err := NewFromTemplate("rails", t.TempDir(), "shop", map[string]string{"root": "~/code/shop"}, ask)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectEqual(t, "session [shop]: ", out.String())
+	if !strings.HasPrefix(data, "session: \"shop-dev\"\n\nroot: \"~/code/shop\"\n") {
+		t.Errorf("unexpected config\n%s", data)
+	}
+
+	data, err = NewFromTemplate("", t.TempDir(), "work", map[string]string{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(data, "session: \"work\"\n\nroot: "+strconv.Quote(currentDir())+"\n") {
+		t.Errorf("expected the default template with the defaults, got\n%s", data)
+	}
+}
@@ -0,0 +1,35 @@
+package tmux
+
+// Capability is a tmux feature gmux uses that older versions don't have.
+type Capability struct {
+	Name  string
+	Since Version
+}
+
+var (
+	CapPaneTitle   = Capability{"select-pane -T", Version{Major: 2, Minor: 6}}
+	CapWindowEnv   = Capability{"new-window -e, split-window -e", Version{Major: 3, Minor: 0}}
+	CapPaneOptions = Capability{"set-option -p", Version{Major: 3, Minor: 0}}
+	CapSizePercent = Capability{"split-window -l N%", Version{Major: 3, Minor: 1}}
+	CapSessionEnv  = Capability{"new-session -e", Version{Major: 3, Minor: 2}}
+)
+
+// Capabilities lists every capability, oldest first.
+var Capabilities = []Capability{
+	CapPaneTitle,
+	CapWindowEnv,
+	CapPaneOptions,
+	CapSizePercent,
+	CapSessionEnv,
+}
+
+// Supports reports whether the version has the capability.
+// An unknown version supports nothing.
+func (v Version) Supports(c Capability) bool {
+	return v.AtLeast(c.Since.Major, c.Since.Minor)
+}
+
+// Requirement returns the "requires tmux ≥ x.y" message for the capability.
+func (c Capability) Requirement() string {
+	return "requires tmux ≥ " + c.Since.String()
+}
@@ -0,0 +1,372 @@
+package tmux
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/aaqaishtyaq/gmux/executor"
+	"github.com/aaqaishtyaq/gmux/internal/slice"
+)
+
+// Notification is a message tmux sends to control mode clients
+// on its own, e.g. %window-add @1 or %exit.
+type Notification struct {
+	// Name without the leading %, e.g. "window-add".
+	Name string
+	// Data is the rest of the line, e.g. "@1".
+	Data string
+}
+
+func (n Notification) Args() []string {
+	return strings.Fields(n.Data)
+}
+
+// ControlClient sends tmux commands over a single control mode (tmux -C)
+// connection instead of starting tmux for each of them. It implements
+// executor.Executor, so it can be used as the Tmux executor.
+//
+// The connection is opened on the first command, attached to the most
+// recently used session. Until a session exists, and for commands that
+// act on the user's client or are not tmux commands at all, Fallback is used.
+type ControlClient struct {
+	Fallback executor.Executor
+	// Path to the server socket, the default server is used if empty.
+	Socket string
+	Logger *log.Logger
+
+	mu            sync.Mutex
+	conn          *controlConn
+	notifications chan Notification
+}
+
+// Commands that depend on the client they are run from.
+var clientCommands = []string{
+	"attach", "attach-session", "switch-client", "switchc",
+	"display-message", "display", "detach-client", "detach",
+}
+
+// Notifications returns the channel notifications are sent to.
+// They are dropped if the channel is full.
+func (c *ControlClient) Notifications() <-chan Notification {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.notificationsChan()
+}
+
+func (c *ControlClient) notificationsChan() chan Notification {
+	if c.notifications == nil {
+		c.notifications = make(chan Notification, 64)
+	}
+
+	return c.notifications
+}
+
+func (c *ControlClient) Exec(cmd *exec.Cmd) (string, error) {
+	commands, ok := c.controlCommands(cmd)
+	if !ok {
+		return c.Fallback.Exec(cmd)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conn, err := c.connect()
+	if err != nil {
+		return c.Fallback.Exec(cmd)
+	}
+
+	// A command list on one line runs without other clients'
+	// commands in between, like it does from the command line.
+	line := strings.Join(commands, " ; ")
+	if c.Logger != nil {
+		c.Logger.Println("control: " + line)
+	}
+
+	out, err := conn.send(line, len(commands))
+	if err != nil {
+		if c.Logger != nil {
+			c.Logger.Println(err)
+		}
+
+		shellErr := &executor.ShellError{Command: strings.Join(cmd.Args, " "), Err: err, ExitCode: -1}
+		var commandErr *controlError
+		if errors.As(err, &commandErr) {
+			shellErr.ExitCode = 1
+			shellErr.Stderr = commandErr.message
+		}
+		return "", shellErr
+	}
+
+	return out, nil
+}
+
+func (c *ControlClient) ExecQuiet(cmd *exec.Cmd) error {
+	if cmd.Stdin != nil || cmd.Stdout != nil || cmd.Stderr != nil {
+		// Attaching blocks until the user detaches,
+		// don't keep an idle client in the session meanwhile.
+		if cmd.Stdin != nil {
+			_ = c.Close()
+		}
+
+		return c.Fallback.ExecQuiet(cmd)
+	}
+
+	_, err := c.Exec(cmd)
+	return err
+}
+
+// Close closes the connection, the tmux client exits.
+func (c *ControlClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.close()
+	c.conn = nil
+	return err
+}
+
+// controlCommands returns the command lines to send for a tmux command,
+// false if it has to be run by the fallback executor.
+func (c *ControlClient) controlCommands(cmd *exec.Cmd) ([]string, bool) {
+	if len(cmd.Args) < 2 || cmd.Args[0] != "tmux" || cmd.Stdin != nil {
+		return nil, false
+	}
+
+	var lines []string
+	for _, args := range SplitCommands(cmd.Args[1:]) {
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") || slice.Contains(clientCommands, args[0]) {
+			return nil, false
+		}
+
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			quoted[i] = quoteArg(arg)
+		}
+		lines = append(lines, strings.Join(quoted, " "))
+	}
+
+	return lines, true
+}
+
+func (c *ControlClient) connect() (*controlConn, error) {
+	if c.conn != nil && !c.conn.closed() {
+		return c.conn, nil
+	}
+
+	args := []string{}
+	if c.Socket != "" {
+		args = append(args, "-S", c.Socket)
+	}
+	args = append(args, "-C", "attach")
+
+	conn, err := dialControl(exec.Command("tmux", args...), c.notificationsChan())
+	if err != nil {
+		return nil, err
+	}
+
+	c.conn = conn
+	return conn, nil
+}
+
+// quoteArg quotes an argument for the tmux command parser.
+// Single quoted strings are taken literally, so only ' needs escaping.
+func quoteArg(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// controlError is a command error reported in a %error block.
+type controlError struct {
+	message string
+}
+
+func (e *controlError) Error() string {
+	return e.message
+}
+
+type controlReply struct {
+	lines []string
+	err   error
+}
+
+// controlConn is a running tmux -C process.
+type controlConn struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	replies chan controlReply
+	done    chan struct{}
+}
+
+func dialControl(cmd *exec.Cmd, notifications chan Notification) (*controlConn, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &controlConn{
+		cmd:     cmd,
+		stdin:   stdin,
+		replies: make(chan controlReply, 1),
+		done:    make(chan struct{}),
+	}
+	go conn.read(stdout, notifications)
+
+	// The first block is the reply to attach.
+	select {
+	case reply := <-conn.replies:
+		if reply.err != nil {
+			_ = conn.close()
+			return nil, reply.err
+		}
+	case <-conn.done:
+		_ = cmd.Wait()
+		return nil, errors.New("tmux control mode client exited")
+	}
+
+	return conn, nil
+}
+
+// read parses the client output. Command output comes in
+// %begin ... %end or %error blocks, other lines starting with %
+// are notifications.
+func (conn *controlConn) read(r io.Reader, notifications chan Notification) {
+	defer close(conn.done)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var block []string
+	inBlock := false
+	fromClient := false
+	first := true
+
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+
+		if inBlock {
+			if strings.HasPrefix(line, "%end ") || strings.HasPrefix(line, "%error ") {
+				inBlock = false
+				if !fromClient && !first {
+					continue
+				}
+				first = false
+
+				reply := controlReply{lines: block}
+				if strings.HasPrefix(line, "%error ") {
+					reply.err = &controlError{strings.Join(block, "\n")}
+				}
+				conn.replies <- reply
+				continue
+			}
+
+			block = append(block, line)
+			continue
+		}
+
+		if strings.HasPrefix(line, "%begin ") {
+			fields := strings.Fields(line)
+			inBlock = true
+			fromClient = len(fields) > 3 && fields[3] == "1"
+			block = nil
+			continue
+		}
+
+		if !strings.HasPrefix(line, "%") {
+			continue
+		}
+
+		name := strings.TrimPrefix(line, "%")
+		data := ""
+		if i := strings.Index(name, " "); i >= 0 {
+			name, data = name[:i], name[i+1:]
+		}
+
+		select {
+		case notifications <- Notification{Name: name, Data: data}:
+		default:
+		}
+
+		if name == "exit" {
+			return
+		}
+	}
+}
+
+func (conn *controlConn) closed() bool {
+	select {
+	case <-conn.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// send writes a command line and waits for the replies of its
+// commands. tmux skips the rest of the line after a failed command.
+func (conn *controlConn) send(line string, commands int) (string, error) {
+	if conn.closed() {
+		return "", errors.New("tmux control mode connection is closed")
+	}
+
+	_, err := fmt.Fprintln(conn.stdin, line)
+	if err != nil {
+		return "", err
+	}
+
+	var outputs []string
+	for i := 0; i < commands; i++ {
+		reply, err := conn.reply()
+		if err != nil {
+			return "", err
+		}
+
+		if out := strings.Join(reply, "\n"); out != "" {
+			outputs = append(outputs, out)
+		}
+	}
+
+	return strings.Join(outputs, "\n"), nil
+}
+
+func (conn *controlConn) reply() ([]string, error) {
+	select {
+	case reply := <-conn.replies:
+		return reply.lines, reply.err
+	case <-conn.done:
+		// The reply could have arrived right before the client exited,
+		// e.g. after killing the session it was attached to.
+		select {
+		case reply := <-conn.replies:
+			return reply.lines, reply.err
+		default:
+			return nil, errors.New("tmux control mode client exited")
+		}
+	}
+}
+
+func (conn *controlConn) close() error {
+	err := conn.stdin.Close()
+	<-conn.done
+	_ = conn.cmd.Wait()
+	return err
+}
@@ -0,0 +1,101 @@
+package tmux
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestControlCommands(t *testing.T) {
+	client := &ControlClient{}
+
+	for _, test := range []struct {
+		args     []string
+		expected []string
+		control  bool
+	}{
+		{
+			[]string{"tmux", "-S", "/tmp/sock", "neww", "-F", "#{window_id}", "-n", "it's"},
+			[]string{`'neww' '-F' '#{window_id}' '-n' 'it'\''s'`},
+			true,
+		},
+		{
+			[]string{"tmux", "send-keys", "-t", "@1", `ls\;`, "Enter", ";", "kill-window", "-t", "@2"},
+			[]string{`'send-keys' '-t' '@1' 'ls;' 'Enter'`, `'kill-window' '-t' '@2'`},
+			true,
+		},
+		{[]string{"tmux", "-V"}, nil, false},
+		{[]string{"tmux", "attach", "-d", "-t", "s"}, nil, false},
+		{[]string{"tmux", "display-message", "-p", "#S"}, nil, false},
+		{[]string{"tmux", "neww", ";", "switch-client", "-t", "s"}, nil, false},
+		{[]string{"/bin/sh", "-c", "true"}, nil, false},
+	} {
+		lines, control := client.controlCommands(exec.Command(test.args[0], test.args[1:]...))
+		if control != test.control || !reflect.DeepEqual(lines, test.expected) {
+			t.Errorf("%v: expected %q (%v), got %q (%v)", test.args, test.expected, test.control, lines, control)
+		}
+	}
+}
+
+func TestControlRead(t *testing.T) {
+	output := strings.Join([]string{
+		"%begin 1700000000 270 0",
+		"%end 1700000000 270 0",
+		"%session-changed $1 project",
+		"%begin 1700000001 271 1",
+		"%5",
+		"%end 1700000001 271 1",
+		"%begin 1700000002 280 0",
+		"from another client",
+		"%end 1700000002 280 0",
+		"%window-add @2",
+		"%begin 1700000003 272 1",
+		"unknown command: bogus",
+		"%error 1700000003 272 1",
+		"%exit",
+		"%window-close @2",
+	}, "\n")
+
+	conn := &controlConn{replies: make(chan controlReply, 10), done: make(chan struct{})}
+	notifications := make(chan Notification, 10)
+	conn.read(strings.NewReader(output), notifications)
+	close(conn.replies)
+	close(notifications)
+
+	var replies []controlReply
+	for reply := range conn.replies {
+		replies = append(replies, reply)
+	}
+
+	if len(replies) != 3 {
+		t.Fatalf("expected 3 replies, got %v", replies)
+	}
+	if replies[0].err != nil || len(replies[0].lines) != 0 {
+		t.Errorf("expected an empty attach reply, got %v", replies[0])
+	}
+	if replies[1].err != nil || !reflect.DeepEqual(replies[1].lines, []string{"%5"}) {
+		t.Errorf("expected %%5, got %v", replies[1])
+	}
+	if replies[2].err == nil || replies[2].err.Error() != "unknown command: bogus" {
+		t.Errorf("expected an error, got %v", replies[2])
+	}
+
+	var received []Notification
+	for n := range notifications {
+		received = append(received, n)
+	}
+
+	expected := []Notification{
+		{Name: "session-changed", Data: "$1 project"},
+		{Name: "window-add", Data: "@2"},
+		{Name: "exit"},
+	}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected %v, got %v", expected, received)
+	}
+
+	if args := received[0].Args(); !reflect.DeepEqual(args, []string{"$1", "project"}) {
+		t.Errorf("unexpected args %v", args)
+	}
+}
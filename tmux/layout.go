@@ -0,0 +1,263 @@
+package tmux
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NamedLayouts are the preset layouts of select-layout.
+var NamedLayouts = []string{EvenHorizontal, EvenVertical, MainHorizontal, MainVertical, Tiled}
+
+// Types of layout cells.
+const (
+	LayoutPane      = "pane"
+	LayoutLeftRight = "left-right"
+	LayoutTopBottom = "top-bottom"
+)
+
+// Layouts start with a checksum of 4 hex digits.
+const layoutChecksumLength = 4
+
+// LayoutCell is a cell of a raw tmux layout, as printed by #{window_layout},
+// e.g. 5719,158x40,0,0{79x40,0,0,0,78x40,80,0,1}.
+// A cell is either a pane or is split into children
+// left to right or top to bottom.
+type LayoutCell struct {
+	Type   string
+	Width  int
+	Height int
+	X      int
+	Y      int
+	// Pane id without %, -1 if the layout doesn't include it.
+	Pane     int
+	Children []*LayoutCell
+}
+
+// IsNamedLayout reports whether layout is one of the NamedLayouts.
+func IsNamedLayout(layout string) bool {
+	for _, named := range NamedLayouts {
+		if layout == named {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LayoutChecksum returns the checksum tmux prefixes layouts with.
+func LayoutChecksum(layout string) string {
+	var csum uint16
+	for i := 0; i < len(layout); i++ {
+		csum = (csum >> 1) + ((csum & 1) << 15)
+		csum += uint16(layout[i])
+	}
+
+	return fmt.Sprintf("%04x", csum)
+}
+
+// ParseLayout parses a raw tmux layout and checks that its checksum
+// matches and that the cells fit together.
+func ParseLayout(layout string) (*LayoutCell, error) {
+	if len(layout) < layoutChecksumLength+1 || layout[layoutChecksumLength] != ',' {
+		return nil, fmt.Errorf("invalid layout %q: expected a checksum", layout)
+	}
+
+	checksum, body := layout[:layoutChecksumLength], layout[layoutChecksumLength+1:]
+	if expected := LayoutChecksum(body); checksum != expected {
+		return nil, fmt.Errorf("invalid layout %q: checksum %s does not match, expected %s", layout, checksum, expected)
+	}
+
+	p := &layoutParser{s: body}
+	cell, err := p.cell()
+	if err == nil && p.pos < len(p.s) {
+		err = p.errorf("unexpected %q", p.s[p.pos:])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid layout %q: %w", layout, err)
+	}
+
+	err = cell.check()
+	if err != nil {
+		return nil, fmt.Errorf("invalid layout %q: %w", layout, err)
+	}
+
+	return cell, nil
+}
+
+// Panes returns the number of panes in the cell.
+func (c *LayoutCell) Panes() int {
+	if c.Type == LayoutPane {
+		return 1
+	}
+
+	n := 0
+	for _, child := range c.Children {
+		n += child.Panes()
+	}
+
+	return n
+}
+
+// String returns the layout of the cell without the checksum.
+func (c *LayoutCell) String() string {
+	s := fmt.Sprintf("%dx%d,%d,%d", c.Width, c.Height, c.X, c.Y)
+	if c.Type == LayoutPane {
+		if c.Pane >= 0 {
+			s += fmt.Sprintf(",%d", c.Pane)
+		}
+		return s
+	}
+
+	children := make([]string, len(c.Children))
+	for i, child := range c.Children {
+		children[i] = child.String()
+	}
+
+	if c.Type == LayoutLeftRight {
+		return s + "{" + strings.Join(children, ",") + "}"
+	}
+
+	return s + "[" + strings.Join(children, ",") + "]"
+}
+
+// check checks that the children of every cell fill it,
+// with a single cell border between them.
+func (c *LayoutCell) check() error {
+	if c.Type == LayoutPane {
+		return nil
+	}
+
+	next, end := c.X, c.X+c.Width
+	if c.Type == LayoutTopBottom {
+		next, end = c.Y, c.Y+c.Height
+	}
+
+	for _, child := range c.Children {
+		var fits bool
+		if c.Type == LayoutLeftRight {
+			fits = child.X == next && child.Y == c.Y && child.Height == c.Height
+			next = child.X + child.Width + 1
+		} else {
+			fits = child.Y == next && child.X == c.X && child.Width == c.Width
+			next = child.Y + child.Height + 1
+		}
+
+		if !fits {
+			return fmt.Errorf("cell %s does not fit in %dx%d,%d,%d", child, c.Width, c.Height, c.X, c.Y)
+		}
+
+		err := child.check()
+		if err != nil {
+			return err
+		}
+	}
+
+	if next-1 != end {
+		return fmt.Errorf("cells do not fill %dx%d,%d,%d", c.Width, c.Height, c.X, c.Y)
+	}
+
+	return nil
+}
+
+type layoutParser struct {
+	s   string
+	pos int
+}
+
+func (p *layoutParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("at %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *layoutParser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+
+	return 0
+}
+
+func (p *layoutParser) expect(b byte) error {
+	if p.peek() != b {
+		return p.errorf("expected %q", b)
+	}
+
+	p.pos++
+	return nil
+}
+
+func (p *layoutParser) number() (int, error) {
+	start := p.pos
+	for p.peek() >= '0' && p.peek() <= '9' {
+		p.pos++
+	}
+
+	if start == p.pos {
+		return 0, p.errorf("expected a number")
+	}
+
+	return strconv.Atoi(p.s[start:p.pos])
+}
+
+// cell parses WxH,X,Y followed by ",pane", "{children}" or "[children]".
+func (p *layoutParser) cell() (*LayoutCell, error) {
+	c := &LayoutCell{Type: LayoutPane, Pane: -1}
+
+	fields := []struct {
+		value *int
+		sep   byte
+	}{{&c.Width, 'x'}, {&c.Height, ','}, {&c.X, ','}, {&c.Y, 0}}
+	for _, field := range fields {
+		n, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+		*field.value = n
+
+		if field.sep != 0 {
+			if err := p.expect(field.sep); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	switch p.peek() {
+	case ',':
+		// A pane id, unless another cell follows.
+		start := p.pos
+		p.pos++
+		n, err := p.number()
+		if err != nil || p.peek() == 'x' {
+			p.pos = start
+			return c, nil
+		}
+		c.Pane = n
+	case '{', '[':
+		closing := byte('}')
+		c.Type = LayoutLeftRight
+		if p.peek() == '[' {
+			closing = ']'
+			c.Type = LayoutTopBottom
+		}
+		p.pos++
+
+		for {
+			child, err := p.cell()
+			if err != nil {
+				return nil, err
+			}
+			c.Children = append(c.Children, child)
+
+			if p.peek() == closing {
+				p.pos++
+				break
+			}
+
+			if err := p.expect(','); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return c, nil
+}
@@ -0,0 +1,52 @@
+package tmux
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLayout(t *testing.T) {
+	for _, test := range []struct {
+		layout string
+		panes  int
+		err    string
+	}{
+		{"5719,158x40,0,0{79x40,0,0,0,78x40,80,0[78x20,80,0,1,78x19,80,21,2]}", 3, ""},
+		{"1a1d,158x40,0,0{79x40,0,0,3,78x40,80,0[78x20,80,0,4,78x19,80,21,5]}", 3, ""},
+		{"5961,80x24,0,0,12", 1, ""},
+		{"347e,80x24,0,0{40x24,0,0,39x24,41,0}", 2, ""},
+		{"ffff,80x24,0,0{40x24,0,0,1,39x24,41,0,2}", 0, "checksum ffff does not match, expected 020a"},
+		{"80x24,0,0", 0, "expected a checksum"},
+		{"01ea,80x24,0,0{40x24,0,0,1,38x24,41,0,2}", 0, "cells do not fill 80x24,0,0"},
+		{"010a,80x24,0,0{40x24,0,0,1,39x23,41,0,2}", 0, "cell 39x23,41,0,2 does not fit in 80x24,0,0"},
+		{"46ab,80x24,0,0{40x24,0,0,1", 0, "expected ','"},
+		{"595b,80x24,0,0,1,", 0, "unexpected \",\""},
+	} {
+		cell, err := ParseLayout(test.layout)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: expected error %q, got %v", test.layout, test.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", test.layout, err)
+			continue
+		}
+
+		if cell.Panes() != test.panes {
+			t.Errorf("%s: expected %d panes, got %d", test.layout, test.panes, cell.Panes())
+		}
+
+		if layout := LayoutChecksum(cell.String()) + "," + cell.String(); layout != test.layout {
+			t.Errorf("expected %s, got %s", test.layout, layout)
+		}
+	}
+}
+
+func TestIsNamedLayout(t *testing.T) {
+	if !IsNamedLayout(Tiled) || IsNamedLayout("tile") {
+		t.Error("expected only tiled to be a named layout")
+	}
+}
@@ -0,0 +1,84 @@
+package tmux
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aaqaishtyaq/gmux/internal/slice"
+)
+
+// OptionsVersion is the tmux version SessionOptions and WindowOptions list
+// the options of. Newer versions can have options they don't list.
+var OptionsVersion = Version{Major: 3, Minor: 3}
+
+// SessionOptions are the session options of tmux 3.3, older versions
+// don't have all of them. They are set in the session scope.
+var SessionOptions = []string{
+	"activity-action", "assume-paste-time", "base-index", "bell-action",
+	"default-command", "default-shell", "default-size", "destroy-unattached",
+	"detach-on-destroy", "display-panes-active-colour", "display-panes-colour",
+	"display-panes-time", "display-time", "history-limit", "key-table",
+	"lock-after-time", "lock-command", "message-command-style", "message-style",
+	"mouse", "prefix", "prefix2", "renumber-windows", "repeat-time", "set-titles",
+	"set-titles-string", "silence-action", "status", "status-bg", "status-fg",
+	"status-interval", "status-justify", "status-keys", "status-left",
+	"status-left-length", "status-left-style", "status-position", "status-right",
+	"status-right-length", "status-right-style", "status-style", "visual-activity",
+	"visual-bell", "visual-silence", "word-separators",
+}
+
+// WindowOptions are the window options of tmux 3.3, older versions
+// don't have all of them. Since tmux 3.0 they can also be set for a single pane.
+var WindowOptions = []string{
+	"aggressive-resize", "allow-passthrough", "allow-rename", "alternate-screen",
+	"automatic-rename", "automatic-rename-format", "clock-mode-colour",
+	"clock-mode-style", "copy-mode-current-match-style", "copy-mode-mark-style",
+	"copy-mode-match-style", "cursor-colour", "cursor-style", "fill-character",
+	"main-pane-height", "main-pane-width", "mode-keys", "mode-style",
+	"monitor-activity", "monitor-bell", "monitor-silence", "other-pane-height",
+	"other-pane-width", "pane-active-border-style", "pane-base-index",
+	"pane-border-format", "pane-border-indicators", "pane-border-lines",
+	"pane-border-status", "pane-border-style", "pane-colours", "popup-border-lines",
+	"popup-border-style", "popup-style", "remain-on-exit", "remain-on-exit-format",
+	"scroll-on-clear", "synchronize-panes", "window-active-style", "window-size",
+	"window-status-activity-style", "window-status-bell-style",
+	"window-status-current-format", "window-status-current-style",
+	"window-status-format", "window-status-last-style", "window-status-separator",
+	"window-status-style", "window-style", "wrap-search", "xterm-keys",
+}
+
+// IsUserOption reports whether the option is a user option, e.g. @project.
+// User options can be set in any scope.
+func IsUserOption(name string) bool {
+	return strings.HasPrefix(name, "@") && len(name) > 1
+}
+
+// IsSessionOption reports whether the option can be set on a session.
+func IsSessionOption(name string) bool {
+	return IsUserOption(name) || slice.Contains(SessionOptions, name)
+}
+
+// IsWindowOption reports whether the option can be set on a window or a pane.
+func IsWindowOption(name string) bool {
+	return IsUserOption(name) || slice.Contains(WindowOptions, name)
+}
+
+// parseOptions parses the output of show-options, one "name value" per line.
+// tmux quotes values with spaces the way Go does.
+func parseOptions(out string) map[string]string {
+	options := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		i := strings.Index(line, " ")
+		if i < 0 {
+			continue
+		}
+
+		name, value := line[:i], line[i+1:]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		options[name] = value
+	}
+
+	return options
+}
@@ -0,0 +1,43 @@
+package tmux
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOptionScopes(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		session bool
+		window  bool
+	}{
+		{"mouse", true, false},
+		{"synchronize-panes", false, true},
+		{"@project", true, true},
+		{"@", false, false},
+		{"mose", false, false},
+	} {
+		if IsSessionOption(test.name) != test.session || IsWindowOption(test.name) != test.window {
+			t.Errorf("%s: expected session %v and window %v", test.name, test.session, test.window)
+		}
+	}
+}
+
+func TestShowOptions(t *testing.T) {
+	executor := &outputExecutor{output: "@gmux_env PORT=3000\nremain-on-exit on\nstatus-left \"a \\\"b\\\" c\"\n@empty \"\""}
+	tmux := Tmux{Executor: executor}
+
+	options, err := tmux.ShowOptions("%1", PaneOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{"remain-on-exit": "on", "status-left": `a "b" c`, "@empty": ""}
+	if !reflect.DeepEqual(expected, options) {
+		t.Errorf("expected %q, got %q", expected, options)
+	}
+
+	if !reflect.DeepEqual([]string{"tmux show-options -p -t %1"}, executor.Commands) {
+		t.Errorf("unexpected commands %q", executor.Commands)
+	}
+}
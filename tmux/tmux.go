@@ -1,8 +1,12 @@
 package tmux
 
 import (
+	"errors"
+	"net/url"
 	"os"
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/aaqaishtyaq/gmux/executor"
@@ -23,65 +27,448 @@ const (
 
 type Tmux struct {
 	Executor executor.Executor
+	// Version of the tmux binary, used to pick the flags it supports.
+	Version Version
+	// Path to the server socket, the default server is used if empty.
+	Socket string
+	// Commands without output are queued here, if set,
+	// and sent to tmux together with the next command.
+	Queue *Queue
+
+	// configPath is the part of the config the queued commands come from, see At.
+	configPath string
 }
 
+// At returns a copy of tmux that queues commands for the part of the config
+// at path, e.g. windows[1].layout, so an error from tmux points at it even if
+// the commands are only sent with a later one.
+func (tmux Tmux) At(path string) Tmux {
+	tmux.configPath = path
+	return tmux
+}
+
+// Queue collects tmux commands gmux doesn't need output from.
+// They are sent to the server together with the next command
+// as `tmux cmd1 ; cmd2 ; ...`, which saves a tmux process per command.
+type Queue struct {
+	commands []queuedCommand
+	// Config paths of the commands in the last batch sent, and whether
+	// the batch set queuedOption after each of them, see take and blame.
+	sent   []string
+	marked bool
+}
+
+type queuedCommand struct {
+	args       []string
+	configPath string
+}
+
+// queuedOption counts the config paths whose commands tmux ran in a batch,
+// tmux stops at the first command that fails.
+const queuedOption = "@gmux_queued"
+
+func (q *Queue) Len() int {
+	if q == nil {
+		return 0
+	}
+
+	return len(q.commands)
+}
+
+// take empties the queue and returns the queued commands followed by args,
+// separated with ";". If the queued commands come from more than one config
+// path, the count of paths done is kept in queuedOption after each of them.
+func (q *Queue) take(args []string) []string {
+	if q == nil {
+		return args
+	}
+
+	q.sent, q.marked = nil, false
+	if q.Len() == 0 {
+		return args
+	}
+
+	labeled := false
+	for i, c := range q.commands {
+		if i == 0 || c.configPath != q.commands[i-1].configPath {
+			q.sent = append(q.sent, c.configPath)
+		}
+		labeled = labeled || c.configPath != ""
+	}
+
+	groups := len(q.sent)
+	if len(args) > 0 {
+		groups++
+	}
+	q.marked = labeled && groups > 1
+
+	var commands [][]string
+	done := 0
+	mark := func() {
+		done++
+		commands = append(commands, []string{"set-option", "-gq", queuedOption, strconv.Itoa(done)})
+	}
+
+	if q.marked {
+		commands = append(commands, []string{"set-option", "-gqu", queuedOption})
+	}
+	for i, c := range q.commands {
+		if q.marked && i > 0 && c.configPath != q.commands[i-1].configPath {
+			mark()
+		}
+		commands = append(commands, c.args)
+	}
+	if q.marked && len(args) > 0 {
+		mark()
+	}
+	commands = append(commands, args)
+	q.commands = nil
+
+	var joined []string
+	for _, c := range commands {
+		if len(c) == 0 {
+			continue
+		}
+
+		if len(joined) > 0 {
+			joined = append(joined, ";")
+		}
+		joined = append(joined, c...)
+	}
+
+	return joined
+}
+
+// escapeArgs escapes a trailing ";", tmux would take it as a command separator.
+func escapeArgs(args []string) []string {
+	escaped := make([]string, len(args))
+	for i, arg := range args {
+		if strings.HasSuffix(arg, ";") {
+			arg = strings.TrimSuffix(arg, ";") + `\;`
+		}
+		escaped[i] = arg
+	}
+
+	return escaped
+}
+
+// SplitCommands splits the arguments of a tmux command line, without the
+// server flags like -S socket, into its `cmd1 ; cmd2` commands,
+// unescaping the arguments escapeArgs escaped.
+func SplitCommands(argv []string) [][]string {
+	for len(argv) > 2 && (argv[0] == "-S" || argv[0] == "-L" || argv[0] == "-f") {
+		argv = argv[2:]
+	}
+
+	commands := [][]string{{}}
+	for _, arg := range argv {
+		if arg == ";" {
+			commands = append(commands, []string{})
+			continue
+		}
+
+		if strings.HasSuffix(arg, `\;`) {
+			arg = strings.TrimSuffix(arg, `\;`) + ";"
+		}
+
+		last := len(commands) - 1
+		commands[last] = append(commands[last], arg)
+	}
+
+	return commands
+}
+
+// command returns a tmux command with the arguments, preceded by the queued commands.
+func (tmux Tmux) command(args ...string) *exec.Cmd {
+	return tmux.commandList(args)
+}
+
+// commandList is command for several commands tmux runs one after the other,
+// without running commands of other clients in between.
+func (tmux Tmux) commandList(commands ...[]string) *exec.Cmd {
+	var args []string
+	for i, c := range commands {
+		if i > 0 {
+			args = append(args, ";")
+		}
+		args = append(args, escapeArgs(c)...)
+	}
+
+	args = tmux.Queue.take(args)
+	if tmux.Socket != "" {
+		args = append([]string{"-S", tmux.Socket}, args...)
+	}
+
+	return exec.Command("tmux", args...)
+}
+
+// run runs a tmux command gmux doesn't need output from,
+// or adds it to the queue if there is one.
+func (tmux Tmux) run(args ...string) error {
+	if tmux.Queue != nil {
+		tmux.Queue.commands = append(tmux.Queue.commands, queuedCommand{escapeArgs(args), tmux.configPath})
+		return nil
+	}
+
+	_, err := tmux.exec(tmux.command(args...))
+	return err
+}
+
+// exec runs a command returned by command with the executor.
+func (tmux Tmux) exec(cmd *exec.Cmd) (string, error) {
+	out, err := tmux.Executor.Exec(cmd)
+	if err != nil {
+		return out, tmux.blame(err)
+	}
+
+	return out, nil
+}
+
+// execQuiet is exec for commands that write to the terminal.
+func (tmux Tmux) execQuiet(cmd *exec.Cmd) error {
+	err := tmux.Executor.ExecQuiet(cmd)
+	if err != nil {
+		return tmux.blame(err)
+	}
+
+	return nil
+}
+
+// blame records the config path of the queued command tmux rejected
+// in the ShellError. It is left empty if the command the queue was
+// sent with failed, the caller knows where that one comes from.
+func (tmux Tmux) blame(err error) error {
+	if tmux.Queue == nil || len(tmux.Queue.sent) == 0 {
+		return err
+	}
+	sent, marked := tmux.Queue.sent, tmux.Queue.marked
+	tmux.Queue.sent = nil
+
+	var shellErr *executor.ShellError
+	if !errors.As(err, &shellErr) || shellErr.ConfigPath != "" {
+		return err
+	}
+
+	done := 0
+	if marked {
+		out, qerr := tmux.Executor.Exec(tmux.command("show-options", "-gqv", queuedOption))
+		if qerr != nil {
+			return err
+		}
+		done, _ = strconv.Atoi(out)
+	}
+
+	if done < len(sent) {
+		shellErr.ConfigPath = sent[done]
+	}
+
+	return err
+}
+
+// Flush sends the queued commands to tmux.
+func (tmux Tmux) Flush() error {
+	if tmux.Queue.Len() == 0 {
+		return nil
+	}
+
+	_, err := tmux.exec(tmux.command())
+	return err
+}
+
+// Scopes of tmux options, see SetOption.
+const (
+	SessionOption = ""
+	WindowOption  = "-w"
+	PaneOption    = "-p"
+)
+
+// User option where gmux records the env a window or a pane was created with,
+// so it can be read back by ListWindows and ListPanes.
+const envOption = "@gmux_env"
+
 type TmuxWindow struct {
 	Id     string
 	Name   string
 	Layout string
 	Root   string
+	Env    map[string]string
+	Active bool
 }
 
 type TmuxPane struct {
-	Root string
+	Id     string
+	Index  string
+	Root   string
+	Env    map[string]string
+	Active bool
+	// Command is the command running in the pane, e.g. zsh or vim.
+	Command string
+	// Title is empty if the pane has the default title, the host name.
+	Title string
+}
+
+// field returns the i-th element of a split format line, empty if it is missing.
+func field(fields []string, i int) string {
+	if i < len(fields) {
+		return fields[i]
+	}
+
+	return ""
+}
+
+func encodeEnv(env map[string]string) string {
+	values := url.Values{}
+	for key, value := range env {
+		values.Set(key, value)
+	}
+
+	return values.Encode()
+}
+
+func decodeEnv(s string) map[string]string {
+	values, err := url.ParseQuery(s)
+	if err != nil || len(values) == 0 {
+		return nil
+	}
+
+	env := make(map[string]string, len(values))
+	for key := range values {
+		env[key] = values.Get(key)
+	}
+
+	return env
+}
+
+// envArgs returns -e flags for the environment variables, sorted by name.
+// They are omitted if the tmux version does not support the flag.
+func envArgs(env map[string]string, supported bool) []string {
+	if !supported {
+		return nil
+	}
+
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var args []string
+	for _, key := range keys {
+		args = append(args, "-e", key+"="+env[key])
+	}
+
+	return args
 }
 
-func (tmux Tmux) NewSession(name string, root string, windowName string) (string, error) {
-	cmd := exec.Command("tmux", "new", "-Pd", "-s", name, "-n", windowName, "-c", root)
-	return tmux.Executor.Exec(cmd)
+func (tmux Tmux) NewSession(name string, root string, windowName string, env map[string]string) (string, error) {
+	args := []string{"new", "-Pd"}
+	args = append(args, envArgs(env, tmux.Version.Supports(CapSessionEnv))...)
+	args = append(args, "-s", name, "-n", windowName, "-c", root)
+
+	cmd := tmux.command(args...)
+	return tmux.exec(cmd)
 }
 
 func (tmux Tmux) SessionExists(name string) bool {
-	cmd := exec.Command("tmux", "has-session", "-t", name)
-	res, err := tmux.Executor.Exec(cmd)
+	cmd := tmux.command("has-session", "-t", name)
+	res, err := tmux.exec(cmd)
 	return res == "" && err == nil
 }
 
 func (tmux Tmux) KillWindow(target string) error {
-	cmd := exec.Command("tmux", "kill-window", "-t", target)
-	_, err := tmux.Executor.Exec(cmd)
-	return err
+	return tmux.run("kill-window", "-t", target)
 }
 
-func (tmux Tmux) NewWindow(target string, name string, root string) (string, error) {
-	cmd := exec.Command("tmux", "neww", "-Pd", "-t", target, "-c", root, "-F", "#{window_id}", "-n", name)
+// NewWindow creates a window and returns its id. The window runs command,
+// if set, instead of the default shell, and stays open after the command
+// exits if remainOnExit is set.
+func (tmux Tmux) NewWindow(target string, name string, root string, env map[string]string, command string, remainOnExit bool) (string, error) {
+	args := []string{"neww", startFlags(remainOnExit)}
+	args = append(args, envArgs(env, tmux.Version.Supports(CapWindowEnv))...)
+	args = append(args, "-t", target, "-c", root, "-F", "#{window_id}", "-n", name)
+	if command != "" {
+		args = append(args, command)
+	}
+
+	cmd := tmux.command(args...)
+	if remainOnExit {
+		cmd = tmux.commandList(args, remainOnExitArgs, []string{"last-window"})
+	}
 
-	return tmux.Executor.Exec(cmd)
+	return tmux.exec(cmd)
 }
 
-func (tmux Tmux) SendKeys(target string, command string) error {
-	cmd := exec.Command("tmux", "send-keys", "-t", target, command, "Enter")
-	return tmux.Executor.ExecQuiet(cmd)
+// remainOnExitArgs set remain-on-exit on the pane started without -d right
+// before them. In the same command list, the option is set before the pane
+// command can exit. last-window or last-pane then reselects the previous one.
+var remainOnExitArgs = []string{"set-option", "-p", "remain-on-exit", "on"}
+
+func startFlags(remainOnExit bool) string {
+	if remainOnExit {
+		return "-P"
+	}
+
+	return "-Pd"
+}
+
+// SendKeys sends tmux key names, e.g. C-c or Up, to the target pane.
+// An argument that is not a key name is typed as is.
+// The keys follow "--", so keys starting with "-" aren't taken for flags.
+func (tmux Tmux) SendKeys(target string, keys ...string) error {
+	return tmux.run(append([]string{"send-keys", "-t", target, "--"}, keys...)...)
+}
+
+// SendLiteral types the text into the target pane without looking up key names.
+func (tmux Tmux) SendLiteral(target string, text ...string) error {
+	return tmux.run(append([]string{"send-keys", "-l", "-t", target, "--"}, text...)...)
+}
+
+// SendCommand types the command into the target pane and presses Enter.
+// Key names in the command are typed as they are.
+func (tmux Tmux) SendCommand(target string, command string) error {
+	err := tmux.SendLiteral(target, command)
+	if err != nil {
+		return err
+	}
+
+	return tmux.SendKeys(target, "Enter")
 }
 
 func (tmux Tmux) Attach(target string, stdin *os.File, stdout *os.File, stderr *os.File) error {
-	cmd := exec.Command("tmux", "attach", "-d", "-t", target)
+	cmd := tmux.command("attach", "-d", "-t", target)
 
 	cmd.Stdin = stdin
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
 
-	return tmux.Executor.ExecQuiet(cmd)
+	return tmux.execQuiet(cmd)
 }
 
 func (tmux Tmux) RenumberWindows(target string) error {
-	cmd := exec.Command("tmux", "move-window", "-r", "-s", target, "-t", target)
-	_, err := tmux.Executor.Exec(cmd)
-	return err
+	return tmux.run("move-window", "-r", "-s", target, "-t", target)
+}
+
+// sizeArgs returns the split-window flags for a size in lines or columns, e.g. 10,
+// or in percent, e.g. 30%. Older tmux versions only take percent with -p.
+func (tmux Tmux) sizeArgs(size string) []string {
+	if size == "" {
+		return nil
+	}
+
+	if strings.HasSuffix(size, "%") && !tmux.Version.Supports(CapSizePercent) {
+		return []string{"-p", strings.TrimSuffix(size, "%")}
+	}
+
+	return []string{"-l", size}
 }
 
-func (tmux Tmux) SplitWindow(target string, splitType string, root string) (string, error) {
-	args := []string{"split-window", "-Pd"}
+// SplitWindow splits the target pane and returns the new pane id.
+// The new pane gets size lines or columns, or a percentage, if size is set,
+// and runs command, if set, instead of the default shell. It stays open after
+// the command exits if remainOnExit is set.
+func (tmux Tmux) SplitWindow(target string, splitType string, size string, root string, env map[string]string, command string, remainOnExit bool) (string, error) {
+	args := []string{"split-window", startFlags(remainOnExit)}
+	args = append(args, envArgs(env, tmux.Version.Supports(CapWindowEnv))...)
 
 	switch splitType {
 	case VSplit:
@@ -89,12 +476,19 @@ func (tmux Tmux) SplitWindow(target string, splitType string, root string) (stri
 	case HSplit:
 		args = append(args, "-h")
 	}
+	args = append(args, tmux.sizeArgs(size)...)
 
 	args = append(args, []string{"-t", target, "-c", root, "-F", "#{pane_id}"}...)
+	if command != "" {
+		args = append(args, command)
+	}
 
-	cmd := exec.Command("tmux", args...)
+	cmd := tmux.command(args...)
+	if remainOnExit {
+		cmd = tmux.commandList(args, remainOnExitArgs, []string{"last-pane"})
+	}
 
-	pane, err := tmux.Executor.Exec(cmd)
+	pane, err := tmux.exec(cmd)
 	if err != nil {
 		return "", err
 	}
@@ -103,28 +497,97 @@ func (tmux Tmux) SplitWindow(target string, splitType string, root string) (stri
 }
 
 func (tmux Tmux) SelectLayout(target string, layoutType string) (string, error) {
-	cmd := exec.Command("tmux", "select-layout", "-t", target, layoutType)
-	return tmux.Executor.Exec(cmd)
+	return "", tmux.run("select-layout", "-t", target, layoutType)
+}
+
+func (tmux Tmux) SelectWindow(target string) error {
+	return tmux.run("select-window", "-t", target)
+}
+
+func (tmux Tmux) SelectPane(target string) error {
+	return tmux.run("select-pane", "-t", target)
 }
 
 func (tmux Tmux) SetEnv(target string, key string, value string) (string, error) {
-	cmd := exec.Command("tmux", "setenv", "-t", target, key, value)
-	return tmux.Executor.Exec(cmd)
+	return "", tmux.run("setenv", "-t", target, key, value)
+}
+
+// SetOption sets a tmux option in the scope, one of SessionOption, WindowOption or PaneOption.
+func (tmux Tmux) SetOption(target string, scope string, option string, value string) error {
+	args := []string{"set-option"}
+	if scope != SessionOption {
+		args = append(args, scope)
+	}
+	args = append(args, "-t", target, option, value)
+
+	return tmux.run(args...)
+}
+
+// ShowOptions returns the options set on the target in the scope,
+// without the options inherited from the global ones or gmux's own.
+func (tmux Tmux) ShowOptions(target string, scope string) (map[string]string, error) {
+	args := []string{"show-options"}
+	if scope != SessionOption {
+		args = append(args, scope)
+	}
+	args = append(args, "-t", target)
+
+	out, err := tmux.exec(tmux.command(args...))
+	if err != nil {
+		return nil, err
+	}
+
+	options := parseOptions(out)
+	delete(options, envOption)
+
+	return options, nil
+}
+
+func (tmux Tmux) SetPaneTitle(target string, title string) error {
+	return tmux.run("select-pane", "-t", target, "-T", title)
+}
+
+// RecordEnv stores the env of a window or a pane in a user option.
+// It does nothing if the env is empty or tmux can't pass env to new panes.
+func (tmux Tmux) RecordEnv(target string, scope string, env map[string]string) error {
+	if len(env) == 0 || !tmux.Version.Supports(CapPaneOptions) {
+		return nil
+	}
+
+	return tmux.SetOption(target, scope, envOption, encodeEnv(env))
 }
 
 func (tmux Tmux) StopSession(target string) (string, error) {
-	cmd := exec.Command("tmux", "kill-session", "-t", target)
-	return tmux.Executor.Exec(cmd)
+	cmd := tmux.command("kill-session", "-t", target)
+	return tmux.exec(cmd)
 }
 
 func (tmux Tmux) SwitchClient(target string) error {
-	cmd := exec.Command("tmux", "switch-client", "-t", target)
-	return tmux.Executor.ExecQuiet(cmd)
+	cmd := tmux.command("switch-client", "-t", target)
+	return tmux.execQuiet(cmd)
+}
+
+// Display returns the format expanded for the target, e.g. #{cursor_x}.
+func (tmux Tmux) Display(target string, format string) (string, error) {
+	cmd := tmux.command("display-message", "-p", "-t", target, format)
+	return tmux.exec(cmd)
+}
+
+// CapturePane returns the history and the visible contents of the target pane
+// with their colours, without the blank lines at the bottom.
+func (tmux Tmux) CapturePane(target string) (string, error) {
+	cmd := tmux.command("capture-pane", "-p", "-e", "-J", "-S", "-", "-t", target)
+	output, err := tmux.exec(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(output, "\n"), nil
 }
 
 func (tmux Tmux) SessionName() (string, error) {
-	cmd := exec.Command("tmux", "display-message", "-p", "#S")
-	sessionName, err := tmux.Executor.Exec(cmd)
+	cmd := tmux.command("display-message", "-p", "#S")
+	sessionName, err := tmux.exec(cmd)
 	if err != nil {
 		return sessionName, err
 	}
@@ -132,11 +595,29 @@ func (tmux Tmux) SessionName() (string, error) {
 	return sessionName, nil
 }
 
+// ListSessions returns the names of the sessions on the server,
+// it fails if no server is running.
+func (tmux Tmux) ListSessions() ([]string, error) {
+	cmd := tmux.command("list-sessions", "-F", "#{session_name}")
+	out, err := tmux.exec(cmd)
+	if err != nil || out == "" {
+		return nil, err
+	}
+
+	return strings.Split(out, "\n"), nil
+}
+
+// ServerSocket returns the path to the socket of the running server.
+func (tmux Tmux) ServerSocket() (string, error) {
+	cmd := tmux.command("display-message", "-p", "#{socket_path}")
+	return tmux.exec(cmd)
+}
+
 func (tmux Tmux) ListWindows(target string) ([]TmuxWindow, error) {
 	var windows []TmuxWindow
 
-	cmd := exec.Command("tmux", "list-windows", "-F", "#{window_id};#{window_name};#{window_layout};#{pane_current_path}", "-t", target)
-	out, err := tmux.Executor.Exec(cmd)
+	cmd := tmux.command("list-windows", "-F", "#{window_id};#{window_name};#{window_layout};#{pane_current_path};#{"+envOption+"};#{window_active}", "-t", target)
+	out, err := tmux.exec(cmd)
 	if err != nil {
 		return windows, err
 	}
@@ -147,9 +628,11 @@ func (tmux Tmux) ListWindows(target string) ([]TmuxWindow, error) {
 		windowInfo := strings.Split(w, ";")
 		window := TmuxWindow{
 			Id:     windowInfo[0],
-			Name:   windowInfo[1],
-			Layout: windowInfo[2],
-			Root:   windowInfo[3],
+			Name:   field(windowInfo, 1),
+			Layout: field(windowInfo, 2),
+			Root:   field(windowInfo, 3),
+			Env:    decodeEnv(field(windowInfo, 4)),
+			Active: field(windowInfo, 5) == "1",
 		}
 		windows = append(windows, window)
 	}
@@ -160,9 +643,10 @@ func (tmux Tmux) ListWindows(target string) ([]TmuxWindow, error) {
 func (tmux Tmux) ListPanes(target string) ([]TmuxPane, error) {
 	var panes []TmuxPane
 
-	cmd := exec.Command("tmux", "list-panes", "-F", "#{pane_current_path}", "-t", target)
+	// The title goes last, it can contain ";".
+	cmd := tmux.command("list-panes", "-F", "#{pane_current_path};#{"+envOption+"};#{pane_active};#{pane_id};#{pane_index};#{pane_current_command};#{host};#{pane_title}", "-t", target)
 
-	out, err := tmux.Executor.Exec(cmd)
+	out, err := tmux.exec(cmd)
 	if err != nil {
 		return panes, err
 	}
@@ -170,9 +654,17 @@ func (tmux Tmux) ListPanes(target string) ([]TmuxPane, error) {
 	panesList := strings.Split(out, "\n")
 
 	for _, p := range panesList {
-		paneInfo := strings.Split(p, ";")
+		paneInfo := strings.SplitN(p, ";", 8)
 		pane := TmuxPane{
-			Root: paneInfo[0],
+			Root:    paneInfo[0],
+			Env:     decodeEnv(field(paneInfo, 1)),
+			Active:  field(paneInfo, 2) == "1",
+			Id:      field(paneInfo, 3),
+			Index:   field(paneInfo, 4),
+			Command: field(paneInfo, 5),
+		}
+		if title := field(paneInfo, 7); title != field(paneInfo, 6) {
+			pane.Title = title
 		}
 
 		panes = append(panes, pane)
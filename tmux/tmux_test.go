@@ -0,0 +1,243 @@
+package tmux
+
+import (
+	"fmt"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type recordingExecutor struct {
+	Commands []string
+}
+
+func (e *recordingExecutor) Exec(cmd *exec.Cmd) (string, error) {
+	e.Commands = append(e.Commands, strings.Join(cmd.Args, " "))
+	return "", nil
+}
+
+func (e *recordingExecutor) ExecQuiet(cmd *exec.Cmd) error {
+	e.Commands = append(e.Commands, strings.Join(cmd.Args, " "))
+	return nil
+}
+
+func TestEnvFlags(t *testing.T) {
+	env := map[string]string{"PORT": "3000", "NODE_ENV": "development"}
+
+	for _, test := range []struct {
+		version  Version
+		expected []string
+	}{
+		{
+			Version{Major: 2, Minor: 9},
+			[]string{
+				"tmux new -Pd -s s -n def -c /root",
+				"tmux neww -Pd -t s: -c /root -F #{window_id} -n win",
+				"tmux split-window -Pd -h -t @1 -c /root -F #{pane_id}",
+			},
+		},
+		{
+			Version{Major: 3, Minor: 0},
+			[]string{
+				"tmux new -Pd -s s -n def -c /root",
+				"tmux neww -Pd -e NODE_ENV=development -e PORT=3000 -t s: -c /root -F #{window_id} -n win",
+				"tmux split-window -Pd -e NODE_ENV=development -e PORT=3000 -h -t @1 -c /root -F #{pane_id}",
+			},
+		},
+		{
+			Version{Major: 3, Minor: 2},
+			[]string{
+				"tmux new -Pd -e NODE_ENV=development -e PORT=3000 -s s -n def -c /root",
+				"tmux neww -Pd -e NODE_ENV=development -e PORT=3000 -t s: -c /root -F #{window_id} -n win",
+				"tmux split-window -Pd -e NODE_ENV=development -e PORT=3000 -h -t @1 -c /root -F #{pane_id}",
+			},
+		},
+	} {
+		executor := &recordingExecutor{}
+		tmux := Tmux{Executor: executor, Version: test.version}
+
+		_, _ = tmux.NewSession("s", "/root", "def", env)
+		_, _ = tmux.NewWindow("s:", "win", "/root", env, "", false)
+		_, _ = tmux.SplitWindow("@1", HSplit, "", "/root", env, "", false)
+
+		if !reflect.DeepEqual(test.expected, executor.Commands) {
+			t.Errorf("tmux %v: expected\n%s\ngot\n%s", test.version, strings.Join(test.expected, "\n"), strings.Join(executor.Commands, "\n"))
+		}
+	}
+}
+
+func TestSizeFlags(t *testing.T) {
+	for _, test := range []struct {
+		version  Version
+		size     string
+		expected string
+	}{
+		{Version{Major: 3, Minor: 3}, "", "tmux split-window -Pd -v -t %1 -c /root -F #{pane_id}"},
+		{Version{Major: 3, Minor: 3}, "10", "tmux split-window -Pd -v -l 10 -t %1 -c /root -F #{pane_id}"},
+		{Version{Major: 3, Minor: 3}, "30%", "tmux split-window -Pd -v -l 30% -t %1 -c /root -F #{pane_id}"},
+		{Version{Major: 3, Minor: 0}, "30%", "tmux split-window -Pd -v -p 30 -t %1 -c /root -F #{pane_id}"},
+	} {
+		executor := &recordingExecutor{}
+		tmux := Tmux{Executor: executor, Version: test.version}
+
+		_, _ = tmux.SplitWindow("%1", VSplit, test.size, "/root", nil, "", false)
+
+		if executor.Commands[0] != test.expected {
+			t.Errorf("tmux %v, size %q: expected %q, got %q", test.version, test.size, test.expected, executor.Commands[0])
+		}
+	}
+}
+
+func TestRemainOnExit(t *testing.T) {
+	executor := &recordingExecutor{}
+	tmux := Tmux{Executor: executor, Version: Version{Major: 3, Minor: 3}}
+
+	_, _ = tmux.NewWindow("s:", "win", "/root", nil, "make", true)
+	_, _ = tmux.SplitWindow("@1", VSplit, "", "/root", nil, "make test", true)
+
+	expected := []string{
+		"tmux neww -P -t s: -c /root -F #{window_id} -n win make ; set-option -p remain-on-exit on ; last-window",
+		"tmux split-window -P -v -t @1 -c /root -F #{pane_id} make test ; set-option -p remain-on-exit on ; last-pane",
+	}
+	if !reflect.DeepEqual(expected, executor.Commands) {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(executor.Commands, "\n"))
+	}
+}
+
+func TestSendKeys(t *testing.T) {
+	executor := &recordingExecutor{}
+	tmux := Tmux{Executor: executor}
+
+	_ = tmux.SendKeys("%1", "C-c", "Up")
+	_ = tmux.SendLiteral("%1", "-la;")
+	_ = tmux.SendCommand("%1", "Escape")
+
+	expected := []string{
+		"tmux send-keys -t %1 -- C-c Up",
+		`tmux send-keys -l -t %1 -- -la\;`,
+		"tmux send-keys -l -t %1 -- Escape",
+		"tmux send-keys -t %1 -- Enter",
+	}
+	if !reflect.DeepEqual(expected, executor.Commands) {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(executor.Commands, "\n"))
+	}
+}
+
+func TestSendCommand(t *testing.T) {
+	executor := &recordingExecutor{}
+	tmux := Tmux{Executor: executor}
+
+	_ = tmux.SendCommand("%1", "-n C-c ls")
+
+	expected := []string{
+		"tmux send-keys -l -t %1 -- -n C-c ls",
+		"tmux send-keys -t %1 -- Enter",
+	}
+	if !reflect.DeepEqual(expected, executor.Commands) {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(executor.Commands, "\n"))
+	}
+}
+
+func TestSocket(t *testing.T) {
+	executor := &recordingExecutor{}
+	tmux := Tmux{Executor: executor, Socket: "/tmp/gmux.sock"}
+
+	_ = tmux.KillWindow("s:w")
+
+	expected := []string{"tmux -S /tmp/gmux.sock kill-window -t s:w"}
+	if !reflect.DeepEqual(expected, executor.Commands) {
+		t.Errorf("expected %q, got %q", expected, executor.Commands)
+	}
+}
+
+type outputExecutor struct {
+	recordingExecutor
+	output string
+}
+
+func (e *outputExecutor) Exec(cmd *exec.Cmd) (string, error) {
+	_, _ = e.recordingExecutor.Exec(cmd)
+	return e.output, nil
+}
+
+func TestQueue(t *testing.T) {
+	executor := &outputExecutor{output: "@1"}
+	tmux := Tmux{Executor: executor, Queue: &Queue{}}
+
+	_ = tmux.SendCommand("s:w", "echo 1;")
+	_, _ = tmux.SelectLayout("s:w", Tiled)
+	if len(executor.Commands) != 0 {
+		t.Fatalf("expected commands to be queued, got %q", executor.Commands)
+	}
+
+	window, err := tmux.NewWindow("s:", "win", "/root", nil, "", false)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if window != "@1" {
+		t.Errorf("expected window id @1, got %q", window)
+	}
+
+	_ = tmux.KillWindow("s:def")
+	if err := tmux.Flush(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := tmux.Flush(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	expected := []string{
+		`tmux send-keys -l -t s:w -- echo 1\; ; send-keys -t s:w -- Enter ; select-layout -t s:w tiled ; neww -Pd -t s: -c /root -F #{window_id} -n win`,
+		"tmux kill-window -t s:def",
+	}
+	if !reflect.DeepEqual(expected, executor.Commands) {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(executor.Commands, "\n"))
+	}
+}
+
+func TestQueueConfigPaths(t *testing.T) {
+	executor := &recordingExecutor{}
+	tmux := Tmux{Executor: executor, Queue: &Queue{}}
+
+	_ = tmux.At("windows[0].title").SetPaneTitle("@1", "shell")
+	_ = tmux.At("windows[0].commands[0]").SendKeys("@1", "make", "Enter")
+	_ = tmux.At("windows[0].commands[0]").SendKeys("@1", "Enter")
+	_, _ = tmux.At("windows[0].layout").SelectLayout("@1", Tiled)
+	if err := tmux.Flush(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	_ = tmux.SelectWindow("@1")
+	if err := tmux.Flush(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	expected := []string{
+		"tmux set-option -gqu @gmux_queued ; select-pane -t @1 -T shell ; set-option -gq @gmux_queued 1 ; " +
+			"send-keys -t @1 -- make Enter ; send-keys -t @1 -- Enter ; set-option -gq @gmux_queued 2 ; select-layout -t @1 tiled",
+		"tmux select-window -t @1",
+	}
+	if !reflect.DeepEqual(expected, executor.Commands) {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(executor.Commands, "\n"))
+	}
+}
+
+// BenchmarkQueue measures building a batch of queued commands,
+// see BenchmarkE2EStart for the time saved against a real tmux.
+func BenchmarkQueue(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		tmux := Tmux{Executor: &recordingExecutor{}, Queue: &Queue{}}
+		for w := 0; w < 12; w++ {
+			target := fmt.Sprintf("@%d", w)
+			_ = tmux.At(fmt.Sprintf("windows[%d].title", w)).SetPaneTitle(target, "shell")
+			_ = tmux.At(fmt.Sprintf("windows[%d].commands[0]", w)).SendKeys(target, "echo 1", "Enter")
+			_, _ = tmux.At(fmt.Sprintf("windows[%d].layout", w)).SelectLayout(target, MainVertical)
+		}
+		if err := tmux.Flush(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,1118 @@
+// Package tmuxtest provides a simulated tmux server for tests.
+//
+// Server implements executor.Executor. It understands the tmux
+// subcommands gmux uses, keeps sessions, windows and panes in memory
+// and generates @N and %N ids like tmux does, so tests can assert on
+// the resulting state instead of the exact command lines.
+package tmuxtest
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aaqaishtyaq/gmux/executor"
+	"github.com/aaqaishtyaq/gmux/tmux"
+)
+
+// Hostname is the #{host} of the fake server.
+const Hostname = "tmuxtest"
+
+// DefaultCommand is the #{pane_current_command} of panes without a Command.
+const DefaultCommand = "zsh"
+
+type Pane struct {
+	Id      string
+	Root    string
+	Env     map[string]string
+	Options map[string]string
+	// Split is the id of the pane this one was split from,
+	// Horizontal is set by split-window -h.
+	Split      string
+	Horizontal bool
+	// Size passed to split-window, e.g. 10 or 30%.
+	Size string
+	// Active is set by select-pane and split-window without -d,
+	// the first pane is active otherwise.
+	Active bool
+	// Title is set by select-pane -T, panes are titled Hostname otherwise.
+	Title string
+	// Command is the #{pane_current_command}, DefaultCommand if empty.
+	Command string
+	// Run is the shell command new-window or split-window got, if any.
+	Run string
+	// Cursor is the #{cursor_x},#{cursor_y} of the pane.
+	Cursor string
+	// History is what capture-pane prints for the pane.
+	History string
+	// Keys holds the arguments of every send-keys call for the pane,
+	// joined with spaces, e.g. "echo 1 Enter", and prefixed with "-l "
+	// for literal keys.
+	Keys []string
+}
+
+type Window struct {
+	Id      string
+	Index   int
+	Name    string
+	Layout  string
+	Options map[string]string
+	Panes   []*Pane
+	// Active is set by select-window and new-window without -d,
+	// the first window is active otherwise.
+	Active bool
+
+	// lastPane is the pane last-pane selects.
+	lastPane *Pane
+}
+
+// ActivePane returns the selected pane, the first one if none was selected.
+func (w *Window) ActivePane() *Pane {
+	for _, p := range w.Panes {
+		if p.Active {
+			return p
+		}
+	}
+
+	return w.Panes[0]
+}
+
+// Pane returns the pane with the index in the window, nil if there is none.
+func (w *Window) Pane(index int) *Pane {
+	if index < 0 || index >= len(w.Panes) {
+		return nil
+	}
+
+	return w.Panes[index]
+}
+
+type Session struct {
+	Name    string
+	Root    string
+	Env     map[string]string
+	Options map[string]string
+	Windows []*Window
+
+	// lastWindow is the window last-window selects.
+	lastWindow *Window
+}
+
+// ActiveWindow returns the selected window, the first one if none was selected.
+func (s *Session) ActiveWindow() *Window {
+	for _, w := range s.Windows {
+		if w.Active {
+			return w
+		}
+	}
+
+	if len(s.Windows) == 0 {
+		return nil
+	}
+
+	return s.Windows[0]
+}
+
+// Window returns the first window with the name, nil if there is none.
+func (s *Session) Window(name string) *Window {
+	for _, w := range s.Windows {
+		if w.Name == name {
+			return w
+		}
+	}
+
+	return nil
+}
+
+// WindowNames returns the names of the session windows in order.
+func (s *Session) WindowNames() []string {
+	names := []string{}
+	for _, w := range s.Windows {
+		names = append(names, w.Name)
+	}
+
+	return names
+}
+
+// Server is an in-memory tmux server.
+type Server struct {
+	// Reported by `tmux -V`, "tmux 3.3a" if empty.
+	Version string
+	// Session of the client, used by display-message.
+	// Set by attach and switch-client.
+	Client string
+	// Cursor of new panes, "2,0" as if after a prompt if empty.
+	Cursor string
+
+	Sessions []*Session
+	// Options are the global options, set with -g.
+	Options map[string]string
+	// Commands holds every command line the server received, tmux or not.
+	Commands []string
+	// External holds the command lines that were not meant for tmux,
+	// e.g. hook commands. They are not run.
+	External []string
+
+	nextWindow int
+	nextPane   int
+	failures   []failure
+	// current is the target of the commands without -t in the command line
+	// being run. Like in tmux, new-window and split-window without -d set it
+	// to the pane they start.
+	current string
+}
+
+type failure struct {
+	prefix string
+	stderr string
+}
+
+func NewServer() *Server {
+	return &Server{}
+}
+
+// AddSession creates a session with a single window named "sh".
+func (s *Server) AddSession(name string, root string) *Session {
+	session := &Session{Name: name, Root: root, Env: map[string]string{}, Options: map[string]string{}}
+	s.Sessions = append(s.Sessions, session)
+	s.addWindow(session, "sh", root, nil)
+
+	return session
+}
+
+// AddWindow creates a window with a single pane at the end of the session.
+func (s *Server) AddWindow(session *Session, name string, root string) *Window {
+	return s.addWindow(session, name, root, nil)
+}
+
+// AddPane appends a pane to the window.
+func (s *Server) AddPane(window *Window, root string) *Pane {
+	pane := s.newPane(root, nil)
+	window.Panes = append(window.Panes, pane)
+
+	return pane
+}
+
+// FailOn makes every command line starting with prefix fail with stderr,
+// and every command of a ";" chain that does as "tmux <command>".
+func (s *Server) FailOn(prefix string, stderr string) {
+	s.failures = append(s.failures, failure{prefix, stderr})
+}
+
+// Session returns the session with the name, nil if there is none.
+func (s *Server) Session(name string) *Session {
+	for _, session := range s.Sessions {
+		if session.Name == name {
+			return session
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) Exec(cmd *exec.Cmd) (string, error) {
+	return s.run(cmd)
+}
+
+func (s *Server) ExecQuiet(cmd *exec.Cmd) error {
+	_, err := s.run(cmd)
+	return err
+}
+
+func (s *Server) run(cmd *exec.Cmd) (string, error) {
+	line := strings.Join(cmd.Args, " ")
+	s.Commands = append(s.Commands, line)
+
+	for _, f := range s.failures {
+		if strings.HasPrefix(line, f.prefix) {
+			return "", s.fail(cmd, f.stderr)
+		}
+	}
+
+	if len(cmd.Args) == 0 || cmd.Args[0] != "tmux" {
+		s.External = append(s.External, line)
+		return "", nil
+	}
+
+	s.current = ""
+
+	var outputs []string
+	for _, command := range tmux.SplitCommands(cmd.Args[1:]) {
+		// Commands sent together fail on their own, after the ones before them ran.
+		for _, f := range s.failures {
+			if strings.HasPrefix("tmux "+strings.Join(command, " "), f.prefix) {
+				return "", s.fail(cmd, f.stderr)
+			}
+		}
+
+		out, err := s.command(command)
+		if err != nil {
+			return "", s.fail(cmd, err.Error())
+		}
+
+		if out != "" {
+			outputs = append(outputs, out)
+		}
+	}
+
+	return strings.Join(outputs, "\n"), nil
+}
+
+func (s *Server) fail(cmd *exec.Cmd, stderr string) error {
+	return &executor.ShellError{
+		Command:  strings.Join(cmd.Args, " "),
+		Err:      errors.New("exit status 1"),
+		ExitCode: 1,
+		Stderr:   stderr,
+		Dir:      cmd.Dir,
+	}
+}
+
+// args holds parsed command flags and positional arguments.
+type args struct {
+	flags      map[string][]string
+	positional []string
+}
+
+func (a args) has(flag string) bool {
+	_, ok := a.flags[flag]
+	return ok
+}
+
+func (a args) get(flag string) string {
+	values := a.flags[flag]
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[len(values)-1]
+}
+
+// parseArgs parses getopt style flags. withValue lists the flags that take a value.
+func parseArgs(argv []string, withValue string) args {
+	a := args{flags: map[string][]string{}}
+
+	for i := 0; i < len(argv); i++ {
+		arg := argv[i]
+		if arg == "--" {
+			a.positional = append(a.positional, argv[i+1:]...)
+			break
+		}
+
+		if len(arg) < 2 || arg[0] != '-' {
+			a.positional = append(a.positional, argv[i:]...)
+			break
+		}
+
+		for j := 1; j < len(arg); j++ {
+			flag := string(arg[j])
+			if !strings.Contains(withValue, flag) {
+				a.flags[flag] = append(a.flags[flag], "")
+				continue
+			}
+
+			value := arg[j+1:]
+			if value == "" && i+1 < len(argv) {
+				i++
+				value = argv[i]
+			}
+			a.flags[flag] = append(a.flags[flag], value)
+			break
+		}
+	}
+
+	return a
+}
+
+func (s *Server) command(argv []string) (string, error) {
+	if len(argv) == 0 {
+		return "", errors.New("no command")
+	}
+
+	name, argv := argv[0], argv[1:]
+	switch name {
+	case "-V":
+		if s.Version == "" {
+			return "tmux 3.3a", nil
+		}
+		return s.Version, nil
+	case "new", "new-session":
+		return s.newSession(parseArgs(argv, "censtxyFf"))
+	case "has-session", "has":
+		_, err := s.findSession(parseArgs(argv, "t").get("t"))
+		return "", err
+	case "kill-session":
+		return "", s.killSession(parseArgs(argv, "t").get("t"))
+	case "neww", "new-window":
+		return s.newWindow(parseArgs(argv, "censtF"))
+	case "kill-window", "killw":
+		return "", s.killWindow(parseArgs(argv, "t").get("t"))
+	case "move-window", "movew":
+		return "", s.moveWindow(parseArgs(argv, "st"))
+	case "split-window", "splitw":
+		return s.splitWindow(parseArgs(argv, "celtpF"))
+	case "send-keys", "send":
+		return "", s.sendKeys(parseArgs(argv, "Nt"))
+	case "select-layout", "selectl":
+		return "", s.selectLayout(parseArgs(argv, "t"))
+	case "select-window", "selectw":
+		return "", s.selectWindow(parseArgs(argv, "t"))
+	case "select-pane", "selectp":
+		return "", s.selectPane(parseArgs(argv, "tT"))
+	case "last-window", "last":
+		return "", s.lastWindow(parseArgs(argv, "t"))
+	case "last-pane", "lastp":
+		return "", s.lastPane(parseArgs(argv, "t"))
+	case "setenv", "set-environment":
+		return "", s.setEnv(parseArgs(argv, "t"))
+	case "set-option", "set":
+		return "", s.setOption(parseArgs(argv, "t"))
+	case "show-options", "show":
+		return s.showOptions(parseArgs(argv, "t"))
+	case "attach", "attach-session", "switch-client", "switchc":
+		session, err := s.findSession(parseArgs(argv, "t").get("t"))
+		if err != nil {
+			return "", err
+		}
+		s.Client = session.Name
+		return "", nil
+	case "capture-pane", "capturep":
+		return s.capturePane(parseArgs(argv, "tSE"))
+	case "display-message", "display":
+		return s.displayMessage(parseArgs(argv, "t"))
+	case "list-sessions", "ls":
+		return s.listSessions(parseArgs(argv, "F"))
+	case "list-windows", "lsw":
+		return s.listWindows(parseArgs(argv, "tF"))
+	case "list-panes", "lsp":
+		return s.listPanes(parseArgs(argv, "tF"))
+	}
+
+	return "", fmt.Errorf("unknown command: %s", name)
+}
+
+func (s *Server) newSession(a args) (string, error) {
+	name := a.get("s")
+	if name == "" {
+		name = strconv.Itoa(len(s.Sessions))
+	}
+
+	if s.Session(name) != nil {
+		return "", fmt.Errorf("duplicate session: %s", name)
+	}
+
+	session := &Session{Name: name, Root: a.get("c"), Env: map[string]string{}, Options: map[string]string{}}
+	s.Sessions = append(s.Sessions, session)
+
+	window := s.addWindow(session, a.get("n"), a.get("c"), parseEnv(a.flags["e"]))
+
+	format := a.get("F")
+	if format == "" {
+		format = "#{session_name}:"
+	}
+
+	return s.expand(format, session, window, window.Panes[0]), nil
+}
+
+func (s *Server) killSession(target string) error {
+	session, err := s.findSession(target)
+	if err != nil {
+		return err
+	}
+
+	for i, existing := range s.Sessions {
+		if existing == session {
+			s.Sessions = append(s.Sessions[:i], s.Sessions[i+1:]...)
+			break
+		}
+	}
+
+	if s.Client == session.Name {
+		s.Client = ""
+	}
+
+	return nil
+}
+
+func (s *Server) addWindow(session *Session, name string, root string, env map[string]string) *Window {
+	index := 0
+	for _, w := range session.Windows {
+		if w.Index >= index {
+			index = w.Index + 1
+		}
+	}
+
+	window := &Window{
+		Id:      fmt.Sprintf("@%d", s.nextWindow),
+		Index:   index,
+		Name:    name,
+		Options: map[string]string{},
+	}
+	s.nextWindow++
+
+	if window.Name == "" {
+		window.Name = "sh"
+	}
+
+	window.Panes = append(window.Panes, s.newPane(root, env))
+	session.Windows = append(session.Windows, window)
+
+	return window
+}
+
+func (s *Server) newPane(root string, env map[string]string) *Pane {
+	pane := &Pane{
+		Id:      fmt.Sprintf("%%%d", s.nextPane),
+		Root:    root,
+		Env:     env,
+		Options: map[string]string{},
+		Cursor:  s.Cursor,
+	}
+	s.nextPane++
+
+	if pane.Cursor == "" {
+		pane.Cursor = "2,0"
+	}
+
+	return pane
+}
+
+func (s *Server) newWindow(a args) (string, error) {
+	session, _, _, err := s.resolve(a.get("t"))
+	if err != nil {
+		return "", err
+	}
+
+	root := a.get("c")
+	if root == "" {
+		root = session.Root
+	}
+
+	window := s.addWindow(session, a.get("n"), root, parseEnv(a.flags["e"]))
+	window.Panes[0].Run = strings.Join(a.positional, " ")
+	if !a.has("d") {
+		activateWindow(session, window)
+		s.current = window.Panes[0].Id
+	}
+
+	format := a.get("F")
+	if format == "" {
+		format = "#{session_name}:#{window_index}.#{pane_index}"
+	}
+
+	return s.expand(format, session, window, window.Panes[0]), nil
+}
+
+func (s *Server) killWindow(target string) error {
+	session, window, _, err := s.resolve(target)
+	if err != nil {
+		return err
+	}
+
+	if window == nil {
+		return fmt.Errorf("can't find window: %s", target)
+	}
+
+	for i, existing := range session.Windows {
+		if existing == window {
+			session.Windows = append(session.Windows[:i], session.Windows[i+1:]...)
+			break
+		}
+	}
+
+	if len(session.Windows) == 0 {
+		return s.killSession(session.Name)
+	}
+
+	return nil
+}
+
+func (s *Server) moveWindow(a args) error {
+	if !a.has("r") {
+		return errors.New("only move-window -r is supported")
+	}
+
+	session, _, _, err := s.resolve(a.get("t"))
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(session.Windows, func(i, j int) bool {
+		return session.Windows[i].Index < session.Windows[j].Index
+	})
+	for i, w := range session.Windows {
+		w.Index = i
+	}
+
+	return nil
+}
+
+func (s *Server) splitWindow(a args) (string, error) {
+	session, window, pane, err := s.resolve(a.get("t"))
+	if err != nil {
+		return "", err
+	}
+
+	if window == nil {
+		return "", fmt.Errorf("can't find window: %s", a.get("t"))
+	}
+
+	root := a.get("c")
+	if root == "" {
+		root = pane.Root
+	}
+
+	newPane := s.newPane(root, parseEnv(a.flags["e"]))
+	newPane.Split = pane.Id
+	newPane.Run = strings.Join(a.positional, " ")
+	newPane.Horizontal = a.has("h")
+	newPane.Size = a.get("l")
+	if percentage := a.get("p"); percentage != "" {
+		newPane.Size = percentage + "%"
+	}
+
+	at := len(window.Panes)
+	for i, p := range window.Panes {
+		if p == pane {
+			at = i + 1
+			break
+		}
+	}
+	window.Panes = append(window.Panes[:at], append([]*Pane{newPane}, window.Panes[at:]...)...)
+	if !a.has("d") {
+		activatePane(window, newPane)
+		s.current = newPane.Id
+	}
+
+	format := a.get("F")
+	if format == "" {
+		format = "#{session_name}:#{window_index}.#{pane_index}"
+	}
+
+	return s.expand(format, session, window, newPane), nil
+}
+
+func (s *Server) selectWindow(a args) error {
+	session, window, _, err := s.resolve(a.get("t"))
+	if err != nil {
+		return err
+	}
+
+	if window == nil {
+		return fmt.Errorf("can't find window: %s", a.get("t"))
+	}
+
+	activateWindow(session, window)
+	return nil
+}
+
+func (s *Server) lastWindow(a args) error {
+	session, _, _, err := s.resolve(s.target(a))
+	if err != nil {
+		return err
+	}
+
+	if session.lastWindow == nil {
+		return errors.New("no last window")
+	}
+
+	activateWindow(session, session.lastWindow)
+	return nil
+}
+
+// activateWindow selects the window, the one selected before becomes the last one.
+func activateWindow(session *Session, window *Window) {
+	if active := session.ActiveWindow(); active != window {
+		session.lastWindow = active
+	}
+
+	for _, w := range session.Windows {
+		w.Active = w == window
+	}
+}
+
+func (s *Server) selectPane(a args) error {
+	_, window, pane, err := s.resolve(a.get("t"))
+	if err != nil {
+		return err
+	}
+
+	if pane == nil {
+		return fmt.Errorf("can't find pane: %s", a.get("t"))
+	}
+
+	if a.has("T") {
+		pane.Title = a.get("T")
+		return nil
+	}
+
+	activatePane(window, pane)
+	return nil
+}
+
+func (s *Server) lastPane(a args) error {
+	_, window, _, err := s.resolve(s.target(a))
+	if err != nil {
+		return err
+	}
+
+	if window == nil || window.lastPane == nil {
+		return errors.New("no last pane")
+	}
+
+	activatePane(window, window.lastPane)
+	return nil
+}
+
+// activatePane selects the pane, the one selected before becomes the last one.
+func activatePane(window *Window, pane *Pane) {
+	if active := window.ActivePane(); active != pane {
+		window.lastPane = active
+	}
+
+	for _, p := range window.Panes {
+		p.Active = p == pane
+	}
+}
+
+func (s *Server) sendKeys(a args) error {
+	_, _, pane, err := s.resolve(a.get("t"))
+	if err != nil {
+		return err
+	}
+
+	if pane == nil {
+		return fmt.Errorf("can't find pane: %s", a.get("t"))
+	}
+
+	keys := strings.Join(a.positional, " ")
+	if a.has("l") {
+		keys = "-l " + keys
+	}
+	pane.Keys = append(pane.Keys, keys)
+	return nil
+}
+
+func (s *Server) selectLayout(a args) error {
+	_, window, _, err := s.resolve(a.get("t"))
+	if err != nil {
+		return err
+	}
+
+	if window == nil {
+		return fmt.Errorf("can't find window: %s", a.get("t"))
+	}
+
+	if len(a.positional) > 0 {
+		window.Layout = a.positional[0]
+	}
+
+	return nil
+}
+
+func (s *Server) setEnv(a args) error {
+	session, err := s.findSession(a.get("t"))
+	if err != nil {
+		return err
+	}
+
+	if len(a.positional) < 2 {
+		return errors.New("setenv: expected a name and a value")
+	}
+
+	session.Env[a.positional[0]] = a.positional[1]
+	return nil
+}
+
+func (s *Server) setOption(a args) error {
+	if len(a.positional) < 1 {
+		return errors.New("set-option: expected an option")
+	}
+
+	value := ""
+	if len(a.positional) > 1 {
+		value = a.positional[1]
+	}
+
+	options, err := s.scopeOptions(a)
+	if err != nil {
+		return err
+	}
+
+	if a.has("u") {
+		delete(options, a.positional[0])
+	} else {
+		options[a.positional[0]] = value
+	}
+
+	return nil
+}
+
+// scopeOptions returns the global options with -g, the options of the pane
+// with -p, of the window with -w and of the session otherwise.
+func (s *Server) scopeOptions(a args) (map[string]string, error) {
+	if a.has("g") {
+		if s.Options == nil {
+			s.Options = map[string]string{}
+		}
+		return s.Options, nil
+	}
+
+	session, window, pane, err := s.resolve(s.target(a))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case a.has("p"):
+		if pane == nil {
+			return nil, fmt.Errorf("can't find pane: %s", a.get("t"))
+		}
+		return pane.Options, nil
+	case a.has("w"):
+		if window == nil {
+			return nil, fmt.Errorf("can't find window: %s", a.get("t"))
+		}
+		return window.Options, nil
+	}
+
+	return session.Options, nil
+}
+
+// showOptions prints the options set in the scope, sorted by name.
+// Like tmux, it quotes values with spaces.
+func (s *Server) showOptions(a args) (string, error) {
+	options, err := s.scopeOptions(a)
+	if err != nil {
+		return "", err
+	}
+
+	// -v shows the value of a single option.
+	if len(a.positional) > 0 && a.has("v") {
+		return options[a.positional[0]], nil
+	}
+
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, len(names))
+	for i, name := range names {
+		value := options[name]
+		if value == "" || strings.ContainsAny(value, " \"\\") {
+			value = strconv.Quote(value)
+		}
+		lines[i] = name + " " + value
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func (s *Server) capturePane(a args) (string, error) {
+	_, _, pane, err := s.resolve(a.get("t"))
+	if err != nil {
+		return "", err
+	}
+
+	if pane == nil {
+		return "", fmt.Errorf("can't find pane: %s", a.get("t"))
+	}
+
+	return pane.History, nil
+}
+
+func (s *Server) displayMessage(a args) (string, error) {
+	if !a.has("p") {
+		return "", nil
+	}
+
+	target := a.get("t")
+	if target == "" {
+		target = s.Client
+	}
+
+	session, window, pane, err := s.resolve(target)
+	if err != nil {
+		return "", err
+	}
+
+	return s.expand(strings.Join(a.positional, " "), session, window, pane), nil
+}
+
+func (s *Server) listSessions(a args) (string, error) {
+	if len(s.Sessions) == 0 {
+		return "", errors.New("no server running")
+	}
+
+	var lines []string
+	for _, session := range s.Sessions {
+		lines = append(lines, s.expand(a.get("F"), session, session.Windows[0], session.Windows[0].Panes[0]))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func (s *Server) listWindows(a args) (string, error) {
+	session, _, _, err := s.resolve(a.get("t"))
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	for _, w := range session.Windows {
+		lines = append(lines, s.expand(a.get("F"), session, w, w.Panes[0]))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func (s *Server) listPanes(a args) (string, error) {
+	session, window, _, err := s.resolve(a.get("t"))
+	if err != nil {
+		return "", err
+	}
+
+	if window == nil {
+		window = session.Windows[0]
+	}
+
+	var lines []string
+	for _, p := range window.Panes {
+		lines = append(lines, s.expand(a.get("F"), session, window, p))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func (s *Server) findSession(target string) (*Session, error) {
+	name := strings.TrimSuffix(target, ":")
+	if i := strings.IndexAny(name, ":."); i >= 0 {
+		name = name[:i]
+	}
+
+	session := s.Session(name)
+	if session == nil {
+		return nil, fmt.Errorf("can't find session: %s", name)
+	}
+
+	return session, nil
+}
+
+// resolve finds the session, window and pane of a target such as
+// "session", "session:", "session:window", "session:window.pane",
+// "@1", "@1.%2" or "%2". The window and the pane default to the first ones.
+// target returns the -t target of the command, the current one if it has none.
+func (s *Server) target(a args) string {
+	if a.has("t") {
+		return a.get("t")
+	}
+
+	return s.current
+}
+
+func (s *Server) resolve(target string) (*Session, *Window, *Pane, error) {
+	if strings.HasPrefix(target, "%") {
+		return s.findPane(target)
+	}
+
+	var windowPart, panePart string
+	sessionPart := target
+	if i := strings.Index(target, ":"); i >= 0 {
+		sessionPart, windowPart = target[:i], target[i+1:]
+	} else if strings.HasPrefix(target, "@") {
+		sessionPart, windowPart = "", target
+	}
+
+	if i := strings.Index(windowPart, "."); i >= 0 {
+		windowPart, panePart = windowPart[:i], windowPart[i+1:]
+	}
+
+	var session *Session
+	var window *Window
+	if sessionPart == "" && strings.HasPrefix(windowPart, "@") {
+		session, window = s.findWindowById(windowPart)
+		if window == nil {
+			return nil, nil, nil, fmt.Errorf("can't find window: %s", windowPart)
+		}
+	} else {
+		var err error
+		session, err = s.findSession(sessionPart)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if windowPart == "" {
+			window = session.ActiveWindow()
+		} else {
+			window = findWindow(session, windowPart)
+			if window == nil {
+				return nil, nil, nil, fmt.Errorf("can't find window: %s", windowPart)
+			}
+		}
+	}
+
+	if window == nil {
+		return session, nil, nil, nil
+	}
+
+	if panePart == "" {
+		return session, window, window.ActivePane(), nil
+	}
+
+	for i, p := range window.Panes {
+		if p.Id == panePart || strconv.Itoa(i) == panePart {
+			return session, window, p, nil
+		}
+	}
+
+	return nil, nil, nil, fmt.Errorf("can't find pane: %s", panePart)
+}
+
+func findWindow(session *Session, target string) *Window {
+	for _, w := range session.Windows {
+		if w.Id == target || w.Name == target || strconv.Itoa(w.Index) == target {
+			return w
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) findWindowById(id string) (*Session, *Window) {
+	for _, session := range s.Sessions {
+		for _, w := range session.Windows {
+			if w.Id == id {
+				return session, w
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *Server) findPane(id string) (*Session, *Window, *Pane, error) {
+	for _, session := range s.Sessions {
+		for _, w := range session.Windows {
+			for _, p := range w.Panes {
+				if p.Id == id {
+					return session, w, p, nil
+				}
+			}
+		}
+	}
+
+	return nil, nil, nil, fmt.Errorf("can't find pane: %s", id)
+}
+
+var formatVariable = regexp.MustCompile(`#\{([^}]*)\}|#S`)
+
+// expand replaces the tmux format variables gmux uses.
+func (s *Server) expand(format string, session *Session, window *Window, pane *Pane) string {
+	return formatVariable.ReplaceAllStringFunc(format, func(v string) string {
+		if v == "#S" {
+			return session.Name
+		}
+
+		name := v[2 : len(v)-1]
+		if strings.HasPrefix(name, "@") {
+			for _, options := range []map[string]string{paneOptions(pane), windowOptions(window), session.Options} {
+				if value, ok := options[name]; ok {
+					return value
+				}
+			}
+			return ""
+		}
+
+		switch name {
+		case "session_name":
+			return session.Name
+		case "window_id":
+			return window.Id
+		case "window_index":
+			return strconv.Itoa(window.Index)
+		case "window_name":
+			return window.Name
+		case "window_layout":
+			return window.Layout
+		case "window_active":
+			return flag(window == session.ActiveWindow())
+		case "pane_id":
+			return pane.Id
+		case "pane_index":
+			for i, p := range window.Panes {
+				if p == pane {
+					return strconv.Itoa(i)
+				}
+			}
+		case "pane_active":
+			return flag(pane == window.ActivePane())
+		case "pane_current_path":
+			return pane.Root
+		case "pane_title":
+			if pane.Title == "" {
+				return Hostname
+			}
+			return pane.Title
+		case "host":
+			return Hostname
+		case "cursor_x", "cursor_y":
+			x, y := pane.Cursor, ""
+			if i := strings.Index(pane.Cursor, ","); i >= 0 {
+				x, y = pane.Cursor[:i], pane.Cursor[i+1:]
+			}
+			if name == "cursor_x" {
+				return x
+			}
+			return y
+		case "pane_current_command":
+			if pane.Command == "" {
+				return DefaultCommand
+			}
+			return pane.Command
+		}
+
+		return ""
+	})
+}
+
+// flag formats a boolean the way tmux does.
+func flag(b bool) string {
+	if b {
+		return "1"
+	}
+
+	return "0"
+}
+
+func paneOptions(pane *Pane) map[string]string {
+	if pane == nil {
+		return nil
+	}
+
+	return pane.Options
+}
+
+func windowOptions(window *Window) map[string]string {
+	if window == nil {
+		return nil
+	}
+
+	return window.Options
+}
+
+func parseEnv(values []string) map[string]string {
+	env := map[string]string{}
+	for _, kv := range values {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+
+	return env
+}
@@ -0,0 +1,125 @@
+package tmuxtest
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func run(t *testing.T, server *Server, args ...string) string {
+	t.Helper()
+
+	out, err := server.Exec(exec.Command("tmux", args...))
+	if err != nil {
+		t.Fatalf("tmux %v: %v", args, err)
+	}
+
+	return out
+}
+
+func TestServer(t *testing.T) {
+	server := NewServer()
+
+	run(t, server, "new", "-Pd", "-s", "work", "-n", "def", "-c", "/root")
+	window := run(t, server, "neww", "-Pd", "-t", "work:", "-c", "/code", "-F", "#{window_id}", "-n", "code")
+	if window != "@1" {
+		t.Errorf("expected window id @1, got %q", window)
+	}
+
+	pane := run(t, server, "split-window", "-Pd", "-h", "-e", "PORT=3000", "-t", window, "-F", "#{pane_id}")
+	if pane != "%2" {
+		t.Errorf("expected pane id %%2, got %q", pane)
+	}
+
+	run(t, server, "send-keys", "-t", window+"."+pane, "make", "Enter")
+	run(t, server, "set-option", "-p", "-t", pane, "@title", "build")
+	run(t, server, "set-option", "-p", "-t", pane, "remain-on-exit", "on")
+	run(t, server, "select-pane", "-t", pane, "-T", "make")
+	run(t, server, "kill-window", "-t", "work:def")
+	run(t, server, "move-window", "-r", "-s", "work:", "-t", "work:")
+
+	out := run(t, server, "list-panes", "-t", "work:code", "-F", "#{window_index};#{pane_index};#{pane_current_path};#{@title}")
+	if out != "0;0;/code;\n0;1;/code;build" {
+		t.Errorf("unexpected list-panes output %q", out)
+	}
+
+	out = run(t, server, "show-options", "-p", "-t", pane)
+	if out != "@title build\nremain-on-exit on" {
+		t.Errorf("unexpected show-options output %q", out)
+	}
+
+	out = run(t, server, "list-panes", "-t", "work:code", "-F", "#{pane_title};#{host}")
+	if out != "tmuxtest;tmuxtest\nmake;tmuxtest" {
+		t.Errorf("unexpected titles %q", out)
+	}
+
+	code := server.Session("work").Window("code")
+	if !reflect.DeepEqual([]string{"make Enter"}, code.Pane(1).Keys) {
+		t.Errorf("unexpected keys %q", code.Pane(1).Keys)
+	}
+
+	if !reflect.DeepEqual(map[string]string{"PORT": "3000"}, code.Pane(1).Env) {
+		t.Errorf("unexpected env %v", code.Pane(1).Env)
+	}
+
+	_, err := server.Exec(exec.Command("tmux", "has-session", "-t", "missing"))
+	if err == nil {
+		t.Errorf("expected has-session to fail for a missing session")
+	}
+}
+
+func TestServerSelect(t *testing.T) {
+	server := NewServer()
+
+	run(t, server, "new", "-Pd", "-s", "work", "-n", "code")
+	run(t, server, "neww", "-Pd", "-t", "work:", "-n", "logs")
+	run(t, server, "split-window", "-d", "-t", "work:code")
+	run(t, server, "select-window", "-t", "work:code")
+	run(t, server, "select-pane", "-t", "work:code.1")
+
+	out := run(t, server, "list-windows", "-t", "work", "-F", "#{window_name};#{window_active}")
+	if out != "code;1\nlogs;0" {
+		t.Errorf("unexpected list-windows output %q", out)
+	}
+
+	out = run(t, server, "list-panes", "-t", "work:code", "-F", "#{pane_index};#{pane_active}")
+	if out != "0;0\n1;1" {
+		t.Errorf("unexpected list-panes output %q", out)
+	}
+}
+
+func TestServerLast(t *testing.T) {
+	server := NewServer()
+
+	run(t, server, "new", "-Pd", "-s", "work", "-n", "code")
+	run(t, server, "neww", "-P", "-t", "work:", "-n", "logs", ";", "set-option", "-p", "remain-on-exit", "on", ";", "last-window")
+	run(t, server, "split-window", "-P", "-t", "work:code", ";", "set-option", "-p", "@title", "build", ";", "last-pane")
+
+	session := server.Session("work")
+	if session.ActiveWindow().Name != "code" || session.Window("code").ActivePane() != session.Window("code").Pane(0) {
+		t.Errorf("expected the first pane of code to be selected again")
+	}
+
+	if session.Window("logs").Pane(0).Options["remain-on-exit"] != "on" || session.Window("code").Pane(1).Options["@title"] != "build" {
+		t.Errorf("expected the options to be set on the new panes")
+	}
+}
+
+func TestServerExternalCommands(t *testing.T) {
+	server := NewServer()
+	server.FailOn("/bin/sh -c fail", "boom")
+
+	err := server.ExecQuiet(exec.Command("/bin/sh", "-c", "echo 1"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	err = server.ExecQuiet(exec.Command("/bin/sh", "-c", "fail"))
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+
+	if !reflect.DeepEqual([]string{"/bin/sh -c echo 1"}, server.External) {
+		t.Errorf("unexpected external commands %q", server.External)
+	}
+}
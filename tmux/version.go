@@ -0,0 +1,68 @@
+package tmux
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version of tmux, e.g. 3.3a. The zero value means the version is unknown.
+type Version struct {
+	Major  int
+	Minor  int
+	Suffix string
+}
+
+// ParseVersion parses the output of `tmux -V`,
+// e.g. "tmux 3.3a" or "tmux next-3.4".
+func ParseVersion(output string) (Version, error) {
+	s := strings.TrimSpace(output)
+	s = strings.TrimPrefix(s, "tmux ")
+	s = strings.TrimPrefix(s, "next-")
+
+	parts := strings.SplitN(s, ".", 2)
+	if len(parts) != 2 {
+		return Version{}, fmt.Errorf("unknown tmux version %q", output)
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return Version{}, fmt.Errorf("unknown tmux version %q", output)
+	}
+
+	i := 0
+	for i < len(parts[1]) && parts[1][i] >= '0' && parts[1][i] <= '9' {
+		i++
+	}
+
+	minor, err := strconv.Atoi(parts[1][:i])
+	if err != nil {
+		return Version{}, fmt.Errorf("unknown tmux version %q", output)
+	}
+
+	return Version{Major: major, Minor: minor, Suffix: parts[1][i:]}, nil
+}
+
+// AtLeast reports whether the version is major.minor or newer.
+func (v Version) AtLeast(major int, minor int) bool {
+	return v.Major > major || (v.Major == major && v.Minor >= minor)
+}
+
+func (v Version) String() string {
+	if v == (Version{}) {
+		return "unknown"
+	}
+
+	return fmt.Sprintf("%d.%d%s", v.Major, v.Minor, v.Suffix)
+}
+
+// DetectVersion asks the tmux binary for its version.
+func (tmux Tmux) DetectVersion() (Version, error) {
+	cmd := tmux.command("-V")
+	out, err := tmux.exec(cmd)
+	if err != nil {
+		return Version{}, err
+	}
+
+	return ParseVersion(out)
+}
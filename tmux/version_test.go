@@ -0,0 +1,64 @@
+package tmux
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	for output, expected := range map[string]Version{
+		"tmux 3.3a":       {3, 3, "a"},
+		"tmux 2.9":        {2, 9, ""},
+		"tmux next-3.4\n": {3, 4, ""},
+		"tmux 3.1-rc2":    {3, 1, "-rc2"},
+	} {
+		v, err := ParseVersion(output)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", output, err)
+		}
+
+		if v != expected {
+			t.Errorf("%q: expected %v, got %v", output, expected, v)
+		}
+	}
+
+	for _, output := range []string{"", "tmux master", "tmux openbsd-7.2"} {
+		_, err := ParseVersion(output)
+		if err == nil {
+			t.Errorf("%q: expected error", output)
+		}
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	v := Version{Major: 3, Minor: 2}
+
+	if !v.AtLeast(3, 0) || !v.AtLeast(2, 9) || !v.AtLeast(3, 2) {
+		t.Errorf("expected %v to be at least 3.0, 2.9 and 3.2", v)
+	}
+
+	if v.AtLeast(3, 3) || v.AtLeast(4, 0) {
+		t.Errorf("expected %v to be older than 3.3 and 4.0", v)
+	}
+
+	if (Version{}).AtLeast(1, 0) {
+		t.Errorf("expected unknown version to support nothing")
+	}
+}
+
+func TestSupports(t *testing.T) {
+	for _, test := range []struct {
+		version  Version
+		expected bool
+	}{
+		{Version{}, false},
+		{Version{Major: 2, Minor: 9, Suffix: "a"}, false},
+		{Version{Major: 3, Minor: 0}, true},
+		{Version{Major: 3, Minor: 3, Suffix: "a"}, true},
+	} {
+		if supports := test.version.Supports(CapWindowEnv); supports != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.version, test.expected, supports)
+		}
+	}
+
+	if requirement := CapSessionEnv.Requirement(); requirement != "requires tmux ≥ 3.2" {
+		t.Errorf("unexpected requirement %q", requirement)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aaqaishtyaq/gmux/config"
+	"github.com/aaqaishtyaq/gmux/tmux"
+)
+
+// checkCapabilities returns an error for every config feature
+// the tmux version doesn't support. Features that gmux can get
+// without the capability, like the session env, are not reported.
+// Nothing is reported if the version is unknown.
+func checkCapabilities(conf config.Config, version tmux.Version) []error {
+	if version == (tmux.Version{}) {
+		return nil
+	}
+
+	var errs []error
+	require := func(path string, c tmux.Capability) {
+		if !version.Supports(c) {
+			errs = append(errs, &config.ValidationError{
+				Path:    path,
+				Message: fmt.Sprintf("%s, found %s", c.Requirement(), version),
+			})
+		}
+	}
+
+	for wIndex, w := range conf.Windows {
+		windowPath := fmt.Sprintf("windows[%d]", wIndex)
+		if len(w.Env) > 0 {
+			require(windowPath+".env", tmux.CapWindowEnv)
+		}
+		if w.RemainOnExit {
+			require(windowPath+".remain_on_exit", tmux.CapPaneOptions)
+		}
+		if w.Title != "" {
+			require(windowPath+".title", tmux.CapPaneTitle)
+		}
+
+		var requirePanes func(path string, panes []config.Pane)
+		requirePanes = func(path string, panes []config.Pane) {
+			for pIndex, p := range panes {
+				panePath := fmt.Sprintf("%s.panes[%d]", path, pIndex)
+				if len(p.Env) > 0 {
+					require(panePath+".env", tmux.CapWindowEnv)
+				}
+				if p.Title != "" {
+					require(panePath+".title", tmux.CapPaneTitle)
+				}
+				if len(p.Options) > 0 {
+					require(panePath+".options", tmux.CapPaneOptions)
+				}
+				if p.RemainOnExit {
+					require(panePath+".remain_on_exit", tmux.CapPaneOptions)
+				}
+				requirePanes(panePath, p.Panes)
+			}
+		}
+		requirePanes(windowPath, w.Panes)
+	}
+
+	return errs
+}
+
+// checkLayouts returns an error for every window layout that is neither
+// a named layout nor a raw tmux layout with as many panes as the window.
+func checkLayouts(conf config.Config) []error {
+	var errs []error
+	for wIndex, w := range conf.Windows {
+		if w.Layout == "" || tmux.IsNamedLayout(w.Layout) {
+			continue
+		}
+
+		path := fmt.Sprintf("windows[%d].layout", wIndex)
+		if !strings.Contains(w.Layout, ",") {
+			errs = append(errs, &config.ValidationError{
+				Path:    path,
+				Message: fmt.Sprintf("unknown layout %q, expected one of %s or a tmux layout", w.Layout, strings.Join(tmux.NamedLayouts, ", ")),
+			})
+			continue
+		}
+
+		layout, err := tmux.ParseLayout(w.Layout)
+		if err != nil {
+			errs = append(errs, &config.ValidationError{Path: path, Message: err.Error()})
+			continue
+		}
+
+		if panes := countPanes(w.Panes) + 1; layout.Panes() != panes {
+			errs = append(errs, &config.ValidationError{
+				Path:    path,
+				Message: fmt.Sprintf("layout has %d panes, the window has %d", layout.Panes(), panes),
+			})
+		}
+	}
+
+	return errs
+}
+
+// checkOptions returns an error for every option tmux doesn't know
+// or can't set in the scope. The session takes session and window options,
+// windows and panes take window options. Options gmux doesn't know are left
+// to a tmux newer than tmux.OptionsVersion, which can have them.
+func checkOptions(conf config.Config, version tmux.Version) []error {
+	newer := version.AtLeast(tmux.OptionsVersion.Major, tmux.OptionsVersion.Minor+1)
+
+	var errs []error
+	check := func(path string, options map[string]string, session bool) {
+		for _, name := range sortedKeys(options) {
+			switch {
+			case tmux.IsWindowOption(name), session && tmux.IsSessionOption(name):
+			case tmux.IsSessionOption(name):
+				errs = append(errs, &config.ValidationError{
+					Path:    path,
+					Message: fmt.Sprintf("%q is a session option, set it in the session options", name),
+				})
+			case !newer:
+				errs = append(errs, &config.ValidationError{
+					Path:    path,
+					Message: fmt.Sprintf("%q is unknown to tmux %s", name, tmux.OptionsVersion),
+				})
+			}
+		}
+	}
+
+	check("options", conf.Options, true)
+	for wIndex, w := range conf.Windows {
+		windowPath := fmt.Sprintf("windows[%d]", wIndex)
+		check(windowPath+".options", w.Options, false)
+
+		var checkPanes func(path string, panes []config.Pane)
+		checkPanes = func(path string, panes []config.Pane) {
+			for pIndex, p := range panes {
+				panePath := fmt.Sprintf("%s.panes[%d]", path, pIndex)
+				check(panePath+".options", p.Options, false)
+				checkPanes(panePath, p.Panes)
+			}
+		}
+		checkPanes(windowPath, w.Panes)
+	}
+
+	return errs
+}
+
+// countPanes returns the number of panes, including nested ones.
+func countPanes(panes []config.Pane) int {
+	n := len(panes)
+	for _, p := range panes {
+		n += countPanes(p.Panes)
+	}
+
+	return n
+}
+
+// Validate returns the problems with the config,
+// including features the installed tmux doesn't support.
+func (gmux Gmux) Validate(conf config.Config) []error {
+	errs := append(conf.Validate(), checkLayouts(conf)...)
+	errs = append(errs, checkOptions(conf, gmux.tmux.Version)...)
+	return append(errs, checkCapabilities(conf, gmux.tmux.Version)...)
+}
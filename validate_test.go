@@ -0,0 +1,145 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aaqaishtyaq/gmux/config"
+	"github.com/aaqaishtyaq/gmux/tmux"
+	"github.com/aaqaishtyaq/gmux/tmux/tmuxtest"
+)
+
+func TestCheckCapabilities(t *testing.T) {
+	conf := config.Config{
+		Session: "work",
+		Env:     map[string]string{"NODE_ENV": "development"},
+		Windows: []config.Window{
+			{Name: "web", Env: map[string]string{"PORT": "3000"}},
+			{Name: "api", Panes: []config.Pane{{}, {Env: map[string]string{"PORT": "3001"}}}},
+			{Name: "logs", Panes: []config.Pane{{Title: "logs", Options: map[string]string{"remain-on-exit": "on"}, RemainOnExit: true}}},
+		},
+	}
+
+	for _, test := range []struct {
+		version  tmux.Version
+		expected []string
+	}{
+		{tmux.Version{}, nil},
+		{tmux.Version{Major: 3, Minor: 0}, nil},
+		{
+			tmux.Version{Major: 2, Minor: 9, Suffix: "a"},
+			[]string{
+				"windows[0].env: requires tmux ≥ 3.0, found 2.9a",
+				"windows[1].panes[1].env: requires tmux ≥ 3.0, found 2.9a",
+				"windows[2].panes[0].options: requires tmux ≥ 3.0, found 2.9a",
+				"windows[2].panes[0].remain_on_exit: requires tmux ≥ 3.0, found 2.9a",
+			},
+		},
+		{
+			tmux.Version{Major: 2, Minor: 5},
+			[]string{
+				"windows[0].env: requires tmux ≥ 3.0, found 2.5",
+				"windows[1].panes[1].env: requires tmux ≥ 3.0, found 2.5",
+				"windows[2].panes[0].title: requires tmux ≥ 2.6, found 2.5",
+				"windows[2].panes[0].options: requires tmux ≥ 3.0, found 2.5",
+				"windows[2].panes[0].remain_on_exit: requires tmux ≥ 3.0, found 2.5",
+			},
+		},
+	} {
+		var messages []string
+		for _, err := range checkCapabilities(conf, test.version) {
+			messages = append(messages, err.Error())
+		}
+
+		if !reflect.DeepEqual(messages, test.expected) {
+			t.Errorf("%v: expected %q, got %q", test.version, test.expected, messages)
+		}
+	}
+}
+
+func TestStartUnsupportedEnv(t *testing.T) {
+	server := tmuxtest.NewServer()
+	gmux := newTestGmux(server)
+	gmux.tmux.Version = tmux.Version{Major: 2, Minor: 9}
+
+	conf := config.Config{
+		Session: "work",
+		Windows: []config.Window{{Name: "web", Env: map[string]string{"PORT": "3000"}}},
+	}
+
+	err := gmux.Start(conf, Options{}, Context{})
+	if err == nil || err.Error() != "windows[0].env: requires tmux ≥ 3.0, found 2.9" {
+		t.Errorf("expected a capability error, got %v", err)
+	}
+
+	if len(server.Commands) != 0 {
+		t.Errorf("expected no tmux commands, got %q", server.Commands)
+	}
+}
+
+func TestCheckLayouts(t *testing.T) {
+	conf := config.Config{
+		Session: "work",
+		Windows: []config.Window{
+			{Name: "default"},
+			{Name: "tiled", Layout: tmux.Tiled},
+			{Name: "raw", Layout: "347e,80x24,0,0{40x24,0,0,39x24,41,0}", Panes: []config.Pane{{}}},
+			{Name: "count", Layout: "347e,80x24,0,0{40x24,0,0,39x24,41,0}"},
+			{Name: "unknown", Layout: "tile"},
+		},
+	}
+
+	var messages []string
+	for _, err := range checkLayouts(conf) {
+		messages = append(messages, err.Error())
+	}
+
+	expected := []string{
+		"windows[3].layout: layout has 2 panes, the window has 1",
+		`windows[4].layout: unknown layout "tile", expected one of even-horizontal, even-vertical, main-horizontal, main-vertical, tiled or a tmux layout`,
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("expected\n%q\ngot\n%q", expected, messages)
+	}
+
+	server := tmuxtest.NewServer()
+	err := newTestGmux(server).Start(conf, Options{}, Context{})
+	if err == nil || err.Error() != expected[0] {
+		t.Errorf("expected start to fail with %q, got %v", expected[0], err)
+	}
+}
+
+func TestCheckOptions(t *testing.T) {
+	conf := config.Config{
+		Session: "work",
+		Options: map[string]string{"mouse": "on", "remain-on-exit": "on", "@project": "work", "mose": "on"},
+		Windows: []config.Window{
+			{Name: "logs", Options: map[string]string{"synchronize-panes": "on", "status": "off"}},
+			{Name: "web", Panes: []config.Pane{{Panes: []config.Pane{{Options: map[string]string{"monitor-activity": "on", "remain": "on"}}}}}},
+		},
+	}
+
+	var messages []string
+	for _, err := range checkOptions(conf, tmux.Version{Major: 3, Minor: 3, Suffix: "a"}) {
+		messages = append(messages, err.Error())
+	}
+
+	expected := []string{
+		`options: "mose" is unknown to tmux 3.3`,
+		`windows[0].options: "status" is a session option, set it in the session options`,
+		`windows[1].panes[0].panes[0].options: "remain" is unknown to tmux 3.3`,
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("expected\n%q\ngot\n%q", expected, messages)
+	}
+
+	// A newer tmux can have options gmux doesn't know, they are left to it.
+	messages = nil
+	for _, err := range checkOptions(conf, tmux.Version{Major: 3, Minor: 4}) {
+		messages = append(messages, err.Error())
+	}
+
+	if !reflect.DeepEqual(messages, expected[1:2]) {
+		t.Errorf("expected\n%q\ngot\n%q", expected[1:2], messages)
+	}
+}
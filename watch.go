@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aaqaishtyaq/gmux/config"
+	"github.com/aaqaishtyaq/gmux/internal/slice"
+	"github.com/aaqaishtyaq/gmux/tmux"
+)
+
+const defaultWatchInterval = 15 * time.Minute
+
+// watchEvents are the control mode notifications that make the watcher
+// save the sessions right away: a session was created or closed, or the
+// watcher's client was detached because its session was closed.
+var watchEvents = []string{"sessions-changed", "exit"}
+
+// ErrWatching is returned by lockWatch when another watcher runs for the server.
+var ErrWatching = errors.New("gmux watch is already running for this tmux server")
+
+// Watch saves a snapshot of the running sessions returned by projects every
+// interval, defaultWatchInterval if 0, and when sessions are created or closed,
+// until the tmux server exits. projects is called before every round,
+// so configs created meanwhile are watched too.
+func (gmux Gmux) Watch(projects func() []string, notifications <-chan tmux.Notification, interval time.Duration, keep int) error {
+	if interval == 0 {
+		interval = defaultWatchInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		sessions := projects()
+
+		running, err := gmux.runningSessions()
+		if err != nil {
+			// The server exited with its last session.
+			return nil
+		}
+
+		for _, session := range sessions {
+			if !slice.Contains(running, session) {
+				continue
+			}
+
+			_, err := gmux.Save(session, snapshotsDir(session), keep, time.Now())
+			if err != nil {
+				fmt.Fprintf(gmux.out(), "Cannot save %s: %s\n", session, strings.TrimSuffix(errorReport(err), "\n"))
+			}
+		}
+
+		waitForChange(ticker.C, notifications)
+	}
+}
+
+// runningSessions lists the sessions on the server. The control client
+// exits with the session it is attached to, the first command after that
+// fails and the second one is sent over a new connection.
+func (gmux Gmux) runningSessions() ([]string, error) {
+	running, err := gmux.tmux.ListSessions()
+	if err != nil {
+		running, err = gmux.tmux.ListSessions()
+	}
+
+	return running, err
+}
+
+// waitForChange blocks until the next tick or one of watchEvents.
+func waitForChange(tick <-chan time.Time, notifications <-chan tmux.Notification) {
+	for {
+		select {
+		case <-tick:
+			return
+		case n, ok := <-notifications:
+			if !ok {
+				notifications = nil
+				continue
+			}
+
+			if slice.Contains(watchEvents, n.Name) {
+				return
+			}
+		}
+	}
+}
+
+// projectSessions returns the session names of the configs in the store.
+func projectSessions(store config.Store) []string {
+	projects, _ := store.List()
+
+	var sessions []string
+	for _, project := range projects {
+		conf, err := config.GetConfig(store.Path(project), map[string]string{})
+		if err != nil || conf.Session == "" {
+			continue
+		}
+
+		sessions = append(sessions, conf.Session)
+	}
+
+	return sessions
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aaqaishtyaq/gmux/config"
+	"github.com/aaqaishtyaq/gmux/tmux"
+	"github.com/aaqaishtyaq/gmux/tmux/tmuxtest"
+)
+
+func TestWatch(t *testing.T) {
+	state := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", state)
+
+	server := tmuxtest.NewServer()
+	server.AddSession("work", "/code")
+	server.AddSession("scratch", "/tmp")
+	gmux := newTestGmux(server)
+
+	notifications := make(chan tmux.Notification, 3)
+	notifications <- tmux.Notification{Name: "window-add", Data: "@3"}
+	notifications <- tmux.Notification{Name: "sessions-changed"}
+	notifications <- tmux.Notification{Name: "exit"}
+
+	rounds := 0
+	projects := func() []string {
+		rounds++
+		// The server exits after the notifications.
+		if rounds == 3 {
+			server.Sessions = nil
+		}
+
+		return []string{"work", "api"}
+	}
+
+	err := gmux.Watch(projects, notifications, time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqual(t, 3, rounds)
+
+	snapshots, err := ListSnapshots(snapshotsDir("work"))
+	if err != nil || len(snapshots) == 0 {
+		t.Errorf("expected work to be saved, got %v, %v", snapshots, err)
+	}
+
+	for _, session := range []string{"scratch", "api"} {
+		if _, err := os.Stat(snapshotsDir(session)); !os.IsNotExist(err) {
+			t.Errorf("expected %s not to be saved, got %v", session, err)
+		}
+	}
+}
+
+func TestProjectSessions(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"work.yaml":   "session: work\n",
+		"api.yaml":    "session: api-server\n",
+		"broken.yaml": "session: [\n",
+		"notes.txt":   "session: notes\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectEqual(t, []string{"api-server", "work"}, projectSessions(config.Store{Dir: dir}))
+}
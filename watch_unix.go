@@ -0,0 +1,47 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// lockWatch locks the watcher lock file of the server listening on socket.
+// The lock is released when the file is closed or the process exits.
+func lockWatch(socket string) (*os.File, error) {
+	name := "watch-" + strings.ReplaceAll(strings.Trim(socket, "/"), "/", "-") + ".lock"
+	path := filepath.Join(stateDir(), name)
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		file.Close()
+		return nil, fmt.Errorf("%w, see %s", ErrWatching, path)
+	}
+
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// The pid is only informative, the lock is what counts.
+	_ = file.Truncate(0)
+	fmt.Fprintln(file, os.Getpid())
+
+	return file, nil
+}
@@ -0,0 +1,39 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestLockWatch(t *testing.T) {
+	state := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", state)
+
+	lock, err := lockWatch("/tmp/tmux-1000/default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEqual(t, filepath.Join(state, "gmux", "watch-tmp-tmux-1000-default.lock"), lock.Name())
+
+	_, err = lockWatch("/tmp/tmux-1000/default")
+	if !errors.Is(err, ErrWatching) {
+		t.Errorf("expected ErrWatching, got %v", err)
+	}
+
+	other, err := lockWatch("/tmp/tmux-1000/work")
+	if err != nil {
+		t.Errorf("expected a lock for another server, got %v", err)
+	}
+	other.Close()
+
+	lock.Close()
+	lock, err = lockWatch("/tmp/tmux-1000/default")
+	if err != nil {
+		t.Errorf("expected the lock to be released, got %v", err)
+	}
+	lock.Close()
+}
@@ -0,0 +1,14 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// lockWatch fails on Windows, there is no tmux server to watch.
+func lockWatch(socket string) (*os.File, error) {
+	return nil, errors.New("gmux watch is not supported on Windows")
+}